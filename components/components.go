@@ -0,0 +1,66 @@
+package components
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+type Component struct {
+	Name      string
+	Paths     []string `mapstructure:"paths"`
+	TagPrefix string   `mapstructure:"tag-prefix"`
+}
+
+// Convert the glob pattern into the regexp. `*` and `?` do not match `/`, `**`
+// matches any number of directories and the pattern matches also all files
+// inside the matched directory.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	runes := []rune(strings.TrimSuffix(pattern, "/"))
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+				if i+1 < len(runes) && runes[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+
+	b.WriteString("(?:/.*)?$")
+
+	return regexp.Compile(b.String())
+}
+
+// Check if any of the changed files matches paths of the component
+func (c Component) IsTouched(files []string) (bool, error) {
+	for _, pattern := range c.Paths {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid path pattern %s: %w", pattern, err)
+		}
+		for _, file := range files {
+			if re.MatchString(file) {
+				log.Printf("[DEBUG] Component %s is touched by %s", c.Name, file)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,40 @@
+package conventional
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/dex4er/gitlab-ci-semver-labels/semver"
+)
+
+var (
+	headerRegexp   = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?: `)
+	breakingRegexp = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// Find the bump level for the Conventional Commits message: major for
+// breaking changes, minor for `feat` and patch for `fix`. Returns an empty
+// string for other messages.
+func BumpLevel(message string) string {
+	header, body, _ := strings.Cut(message, "\n")
+
+	matches := headerRegexp.FindStringSubmatch(header)
+	if matches == nil {
+		log.Printf("[TRACE] not a conventional commit: %s", header)
+		return ""
+	}
+
+	if matches[2] == "!" || breakingRegexp.MatchString(body) {
+		return semver.LevelMajor
+	}
+
+	switch strings.ToLower(matches[1]) {
+	case "feat":
+		return semver.LevelMinor
+	case "fix":
+		return semver.LevelPatch
+	}
+
+	return ""
+}
@@ -1,7 +1,11 @@
 package git
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
@@ -14,20 +18,41 @@ import (
 	"github.com/dex4er/gitlab-ci-semver-labels/semver"
 )
 
+const (
+	TagOrderSemver   = "semver"
+	TagOrderTime     = "time"
+	TagOrderTopology = "topology"
+)
+
 type FindLastTagParams struct {
 	RepositoryPath string
 	RemoteName     string
 	GitlabToken    string
 	FetchTags      bool
+	AllTags        bool
+	TagOrder       string
+	TagPrefix      string
+	// Optional callback for the decision about each tag
+	OnTag func(TagDecision)
+}
+
+// Decision if the tag is considered as the last tag
+type TagDecision struct {
+	Tag      string
+	Accepted bool
+	Reason   string
 }
 
 func FindLastTag(params FindLastTagParams) (string, error) {
 	log.Printf(
-		"[TRACE] FindLastTag(RepositoryPath=%v, RemoteName=%v, GitlabToken=%v, FetchTags=%v)",
+		"[TRACE] FindLastTag(RepositoryPath=%v, RemoteName=%v, GitlabToken=%v, FetchTags=%v, AllTags=%v, TagOrder=%v, TagPrefix=%v)",
 		params.RepositoryPath,
 		params.RemoteName,
 		params.GitlabToken,
 		params.FetchTags,
+		params.AllTags,
+		params.TagOrder,
+		params.TagPrefix,
 	)
 
 	// Open the repository
@@ -60,16 +85,160 @@ func FindLastTag(params FindLastTagParams) (string, error) {
 		return "", err
 	}
 
-	// Find the most recent tag that points to the HEAD commit
-	tag, err := findMostRecentTagForCommit(repo, commitObj)
+	// Find the last tag reachable from the HEAD commit
+	tag, err := findLastTagForCommit(repo, commitObj, params.AllTags, params.TagOrder, params.TagPrefix, params.OnTag)
 	if err != nil {
-		log.Printf("[TRACE] error after findMostRecentTagForCommit(repo, commitObj)")
+		log.Printf("[TRACE] error after findLastTagForCommit(repo, commitObj, %v, %v, %v)", params.AllTags, params.TagOrder, params.TagPrefix)
 		return "", err
 	}
 
 	return tag, nil
 }
 
+type ListTagsParams struct {
+	RepositoryPath string
+	RemoteName     string
+	GitlabToken    string
+	FetchTags      bool
+	TagPrefix      string
+}
+
+// Semver tag with the commit it points to
+type Tag struct {
+	Name    string
+	Version string
+	Commit  string
+	Time    time.Time
+	// Tagger of the annotated tag or author of the commit
+	Tagger    string
+	Annotated bool
+	Reachable bool
+}
+
+// List all semver tags with the given prefix sorted by the semver precedence
+// from the lowest
+func ListTags(params ListTagsParams) ([]Tag, error) {
+	log.Printf(
+		"[TRACE] ListTags(RepositoryPath=%v, RemoteName=%v, GitlabToken=%v, FetchTags=%v, TagPrefix=%v)",
+		params.RepositoryPath,
+		params.RemoteName,
+		params.GitlabToken,
+		params.FetchTags,
+		params.TagPrefix,
+	)
+
+	repo, err := git.PlainOpen(params.RepositoryPath)
+	if err != nil {
+		log.Printf("[TRACE] error after git.PlainOpen(%v)", params.RepositoryPath)
+		return nil, err
+	}
+
+	if params.FetchTags {
+		err = fetchTags(repo, params.RemoteName, params.GitlabToken)
+		if err != nil {
+			log.Printf("[TRACE] error after fetchTags(repo, %v, %v)", params.RemoteName, params.GitlabToken)
+			return nil, err
+		}
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		log.Printf("[TRACE] error after repo.Head()")
+		return nil, err
+	}
+
+	commitObj, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		log.Printf("[TRACE] error after repo.CommitObject(ref.Hash())")
+		return nil, err
+	}
+
+	candidates, err := findSemverTags(repo, params.TagPrefix, nil)
+	if err != nil {
+		log.Printf("[TRACE] error after findSemverTags(repo, %v, nil)", params.TagPrefix)
+		return nil, err
+	}
+
+	distances, err := findReachableCommits(repo, commitObj)
+	if err != nil {
+		log.Printf("[TRACE] error after findReachableCommits(repo, %v)", commitObj.Hash)
+		return nil, err
+	}
+
+	// the same version with different build metadata is ordered by time
+	var sortErr error
+	sort.SliceStable(candidates, func(i, j int) bool {
+		cmp, err := semver.Compare(candidates[i].version, candidates[j].version)
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return candidates[i].time.Before(candidates[j].time)
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	tags := make([]Tag, 0, len(candidates))
+	for _, candidate := range candidates {
+		_, reachable := distances[candidate.commit]
+		tags = append(tags, Tag{
+			Name:      candidate.name,
+			Version:   candidate.version,
+			Commit:    candidate.commit.String(),
+			Time:      candidate.time,
+			Tagger:    fmt.Sprintf("%s <%s>", candidate.signature.Name, candidate.signature.Email),
+			Annotated: candidate.annotated,
+			Reachable: reachable,
+		})
+	}
+
+	return tags, nil
+}
+
+type FetchTagsParams struct {
+	RepositoryPath string
+	RemoteName     string
+	GitlabToken    string
+}
+
+// Fetch all tags from the remote, so later lookups can skip fetching
+func FetchTags(params FetchTagsParams) error {
+	log.Printf(
+		"[TRACE] FetchTags(RepositoryPath=%v, RemoteName=%v, GitlabToken=%v)",
+		params.RepositoryPath,
+		params.RemoteName,
+		params.GitlabToken,
+	)
+
+	repo, err := git.PlainOpen(params.RepositoryPath)
+	if err != nil {
+		log.Printf("[TRACE] error after git.PlainOpen(%v)", params.RepositoryPath)
+		return err
+	}
+
+	return fetchTags(repo, params.RemoteName, params.GitlabToken)
+}
+
+// Check if the repository is the shallow clone with the truncated history
+func IsShallow(repositoryPath string) (bool, error) {
+	repo, err := git.PlainOpen(repositoryPath)
+	if err != nil {
+		log.Printf("[TRACE] error after git.PlainOpen(%v)", repositoryPath)
+		return false, err
+	}
+
+	shallows, err := repo.Storer.Shallow()
+	if err != nil {
+		return false, err
+	}
+
+	return len(shallows) > 0, nil
+}
+
 // Add HTTP Basic Authorization to Git client
 func getAuth(accessToken string) transport.AuthMethod {
 	if accessToken != "" {
@@ -95,68 +264,543 @@ func fetchTags(repo *git.Repository, remoteName string, accessToken string) erro
 	return nil
 }
 
-// Find the most recent tag that points to the given commit or annotated tag object
-func findMostRecentTagForCommit(repo *git.Repository, commitObj *object.Commit) (string, error) {
-	log.Printf("[TRACE] findMostRecentTagForCommit(repo=%v, commitObj=%v)", repo, commitObj)
+// Semver tag with the commit it points to
+type tagCandidate struct {
+	name    string
+	version string
+	commit  plumbing.Hash
+	time    time.Time
+	// tagger of the annotated tag or author of the commit
+	signature object.Signature
+	annotated bool
+}
+
+// Find distances from the given commit to all its ancestors
+func findReachableCommits(repo *git.Repository, commitObj *object.Commit) (map[plumbing.Hash]int, error) {
+	distances := map[plumbing.Hash]int{commitObj.Hash: 0}
+	queue := []*object.Commit{commitObj}
+	truncated := false
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, parentHash := range current.ParentHashes {
+			if _, ok := distances[parentHash]; ok {
+				continue
+			}
+			parentObj, err := repo.CommitObject(parentHash)
+			if err == plumbing.ErrObjectNotFound {
+				// shallow clone
+				log.Printf("[DEBUG] parent commit %s not found, history is truncated", parentHash)
+				truncated = true
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			distances[parentHash] = distances[current.Hash] + 1
+			queue = append(queue, parentObj)
+		}
+	}
+
+	if truncated {
+		log.Println("[WARNING] History is truncated, tags beyond the shallow clone are not reachable. Fetch full history, ie. with GIT_DEPTH: 0")
+	}
+
+	return distances, nil
+}
+
+// Find the commit of the lightweight or annotated tag. The signature is the
+// tagger of the annotated tag or the author of the commit.
+func resolveTag(repo *git.Repository, ref *plumbing.Reference) (*object.Commit, object.Signature, bool, error) {
+	refHash := ref.Hash()
+
+	tagObj, err := repo.TagObject(refHash)
+	if err == nil {
+		log.Printf("[TRACE] tagObj=%v", tagObj)
+		commitObj, err := tagObj.Commit()
+		if err != nil {
+			return nil, object.Signature{}, false, err
+		}
+		return commitObj, tagObj.Tagger, true, nil
+	}
+
+	commitObj, err := repo.CommitObject(refHash)
+	if err != nil {
+		return nil, object.Signature{}, false, err
+	}
+	log.Printf("[TRACE] commitObj=%v", commitObj)
+	return commitObj, commitObj.Author, false, nil
+}
+
+// Find all semver tags with the given prefix and commits they point to
+func findSemverTags(repo *git.Repository, tagPrefix string, onTag func(TagDecision)) ([]tagCandidate, error) {
 	tagRefs, err := repo.Tags()
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	reject := func(tag string, reason string) {
+		if onTag != nil {
+			onTag(TagDecision{Tag: tag, Reason: reason})
+		}
 	}
 
-	mostRecentTag := ""
-	var mostRecentCommitTime time.Time
+	candidates := []tagCandidate{}
 
 	err = tagRefs.ForEach(func(ref *plumbing.Reference) error {
-		log.Printf("[TRACE] findMostRecentTagForCommit tagRefs.ForEach(ref=%v)", ref)
-
-		var tagTime time.Time
-
-		if ref.Type() != plumbing.SymbolicReference {
-			refHash := ref.Hash()
-			tagObj, err := repo.TagObject(refHash)
-
-			if err == nil {
-				log.Printf("[TRACE] tagObj=%v", tagObj)
-				tagTime = tagObj.Tagger.When
-			} else {
-				commitObj, err := repo.CommitObject(refHash)
-				if err == nil {
-					log.Printf("[TRACE] commitObj=%v", commitObj)
-					tagTime = commitObj.Author.When
-				} else {
-					log.Printf("[DEBUG] no commit nor annotated tag for a given hash: %s: %v", refHash, err)
-					return nil
-				}
+		log.Printf("[TRACE] findSemverTags tagRefs.ForEach(ref=%v)", ref)
+
+		if ref.Type() == plumbing.SymbolicReference {
+			return nil
+		}
+
+		commitObj, signature, annotated, err := resolveTag(repo, ref)
+		if err != nil {
+			log.Printf("[DEBUG] no commit for a given tag: %s: %v", ref.Hash(), err)
+			reject(ref.Name().Short(), "no commit for the tag")
+			return nil
+		}
+
+		tag := ref.Name().Short()
+		log.Printf("[DEBUG] Found tag: %s", tag)
+
+		if !strings.HasPrefix(tag, tagPrefix) {
+			log.Printf("[DEBUG] %v does not have prefix %v", tag, tagPrefix)
+			reject(tag, fmt.Sprintf("does not have prefix %s", tagPrefix))
+			return nil
+		}
+
+		version := strings.TrimPrefix(tag, tagPrefix)
+
+		if !semver.IsValid(version) {
+			log.Printf("[WARNING] %v is not a valid semver", tag)
+			reject(tag, "not a valid semver")
+			return nil
+		}
+
+		candidates = append(candidates, tagCandidate{
+			name:      tag,
+			version:   version,
+			commit:    commitObj.Hash,
+			time:      signature.When,
+			signature: signature,
+			annotated: annotated,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// Check if the candidate tag should be the last one instead of the current
+// winner
+func isLaterTag(candidate tagCandidate, candidateDistance int, winner tagCandidate, winnerDistance int, tagOrder string) (bool, error) {
+	compareSemver := func() (int, error) {
+		return semver.Compare(candidate.version, winner.version)
+	}
+
+	switch tagOrder {
+	case TagOrderSemver:
+		cmp, err := compareSemver()
+		if err != nil {
+			return false, err
+		}
+		if cmp != 0 {
+			return cmp > 0, nil
+		}
+		return candidate.time.After(winner.time), nil
+
+	case TagOrderTime:
+		return candidate.time.After(winner.time), nil
+
+	case TagOrderTopology:
+		// unreachable tags have negative distance
+		if candidateDistance != winnerDistance {
+			if winnerDistance < 0 {
+				return true, nil
+			}
+			if candidateDistance < 0 {
+				return false, nil
 			}
+			return candidateDistance < winnerDistance, nil
+		}
+		cmp, err := compareSemver()
+		if err != nil {
+			return false, err
+		}
+		if cmp != 0 {
+			return cmp > 0, nil
+		}
+		return candidate.time.After(winner.time), nil
+	}
+
+	return false, fmt.Errorf("unknown tag order: %s", tagOrder)
+}
+
+// Find the last tag reachable from the given commit. The tag order decides
+// if the highest version, the most recent or the nearest tag wins. If allTags
+// is set then also unreachable tags are considered. Only tags with the tag
+// prefix are considered.
+func findLastTagForCommit(repo *git.Repository, commitObj *object.Commit, allTags bool, tagOrder string, tagPrefix string, onTag func(TagDecision)) (string, error) {
+	log.Printf("[TRACE] findLastTagForCommit(repo=%v, commitObj=%v, allTags=%v, tagOrder=%v, tagPrefix=%v)", repo, commitObj, allTags, tagOrder, tagPrefix)
+
+	switch tagOrder {
+	case "":
+		tagOrder = TagOrderSemver
+	case TagOrderSemver, TagOrderTime, TagOrderTopology:
+	default:
+		return "", fmt.Errorf("unknown tag order: %s", tagOrder)
+	}
+
+	candidates, err := findSemverTags(repo, tagPrefix, onTag)
+	if err != nil {
+		return "", err
+	}
+
+	var distances map[plumbing.Hash]int
+	if !allTags || tagOrder == TagOrderTopology {
+		distances, err = findReachableCommits(repo, commitObj)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var lastTag tagCandidate
+	lastDistance := -1
 
-			tag := ref.Name().Short()
-			log.Printf("[DEBUG] Found tag: %s", tag)
-
-			if semver.IsValid(tag) {
-				if mostRecentTag == "" {
-					mostRecentTag = tag
-					mostRecentCommitTime = tagTime
-					log.Printf("[TRACE] mostRecentTag=%v", mostRecentTag)
-				} else {
-					commitTime := tagTime
-
-					if commitTime.After(mostRecentCommitTime) {
-						mostRecentTag = tag
-						mostRecentCommitTime = commitTime
-					}
-					log.Printf("[TRACE] mostRecentTag=%v", mostRecentTag)
+	for _, candidate := range candidates {
+		distance, ok := distances[candidate.commit]
+		if !ok {
+			if !allTags {
+				log.Printf("[DEBUG] %v is not reachable from %v", candidate.name, commitObj.Hash)
+				if onTag != nil {
+					onTag(TagDecision{Tag: candidate.name, Reason: "not reachable from HEAD"})
 				}
-			} else {
-				log.Printf("[WARNING] %v is not a valid semver", tag)
+				continue
 			}
+			distance = -1
 		}
 
-		return nil
+		if onTag != nil {
+			onTag(TagDecision{Tag: candidate.name, Accepted: true, Reason: fmt.Sprintf("candidate by %s order", tagOrder)})
+		}
+
+		if lastTag.name == "" {
+			lastTag = candidate
+			lastDistance = distance
+		} else {
+			later, err := isLaterTag(candidate, distance, lastTag, lastDistance, tagOrder)
+			if err != nil {
+				return "", err
+			}
+			if later {
+				lastTag = candidate
+				lastDistance = distance
+			}
+		}
+		log.Printf("[TRACE] lastTag=%v", lastTag.name)
+	}
+
+	return lastTag.name, nil
+}
+
+type ChangedFilesParams struct {
+	RepositoryPath string
+	Tag            string
+}
+
+// List files changed between the tag and HEAD. All files from HEAD are listed
+// if there is no tag.
+func ChangedFiles(params ChangedFilesParams) ([]string, error) {
+	log.Printf(
+		"[TRACE] ChangedFiles(RepositoryPath=%v, Tag=%v)",
+		params.RepositoryPath,
+		params.Tag,
+	)
+
+	repo, err := git.PlainOpen(params.RepositoryPath)
+	if err != nil {
+		log.Printf("[TRACE] error after git.PlainOpen(%v)", params.RepositoryPath)
+		return nil, err
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		log.Printf("[TRACE] error after repo.Head()")
+		return nil, err
+	}
+
+	headObj, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		log.Printf("[TRACE] error after repo.CommitObject(ref.Hash())")
+		return nil, err
+	}
+
+	headTree, err := headObj.Tree()
+	if err != nil {
+		log.Printf("[TRACE] error after headObj.Tree()")
+		return nil, err
+	}
+
+	files := []string{}
+
+	if params.Tag == "" {
+		err = headTree.Files().ForEach(func(file *object.File) error {
+			files = append(files, file.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return files, nil
+	}
+
+	tagRef, err := repo.Tag(params.Tag)
+	if err != nil {
+		log.Printf("[TRACE] error after repo.Tag(%v)", params.Tag)
+		return nil, err
+	}
+
+	tagObj, _, _, err := resolveTag(repo, tagRef)
+	if err != nil {
+		log.Printf("[TRACE] error after resolveTag(repo, %v)", tagRef)
+		return nil, err
+	}
+
+	tagTree, err := tagObj.Tree()
+	if err != nil {
+		log.Printf("[TRACE] error after tagObj.Tree()")
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(tagTree, headTree)
+	if err != nil {
+		log.Printf("[TRACE] error after object.DiffTree(tagTree, headTree)")
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+type CreateTagParams struct {
+	RepositoryPath string
+	RemoteName     string
+	GitlabToken    string
+	Tag            string
+	Message        string
+	TaggerName     string
+	TaggerEmail    string
+}
+
+// Create the annotated tag on HEAD and push it to the remote
+func CreateTag(params CreateTagParams) error {
+	log.Printf(
+		"[TRACE] CreateTag(RepositoryPath=%v, RemoteName=%v, GitlabToken=%v, Tag=%v, Message=%v, TaggerName=%v, TaggerEmail=%v)",
+		params.RepositoryPath,
+		params.RemoteName,
+		params.GitlabToken,
+		params.Tag,
+		params.Message,
+		params.TaggerName,
+		params.TaggerEmail,
+	)
+
+	repo, err := git.PlainOpen(params.RepositoryPath)
+	if err != nil {
+		log.Printf("[TRACE] error after git.PlainOpen(%v)", params.RepositoryPath)
+		return err
+	}
+
+	remote, err := repo.Remote(params.RemoteName)
+	if err != nil {
+		log.Printf("[TRACE] error after repo.Remote(%v)", params.RemoteName)
+		return err
+	}
+
+	tagRefName := plumbing.NewTagReferenceName(params.Tag)
+
+	// Check if the tag already exists upstream
+	remoteRefs, err := remote.List(&git.ListOptions{
+		Auth: getAuth(params.GitlabToken),
 	})
+	if err != nil {
+		log.Printf("[TRACE] error after remote.List()")
+		return err
+	}
+	for _, ref := range remoteRefs {
+		if ref.Name() == tagRefName {
+			return fmt.Errorf("tag %s already exists in remote %s", params.Tag, params.RemoteName)
+		}
+	}
 
+	ref, err := repo.Head()
 	if err != nil {
-		return "", err
+		log.Printf("[TRACE] error after repo.Head()")
+		return err
+	}
+
+	tagger, err := getTagger(repo, params.TaggerName, params.TaggerEmail)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.CreateTag(params.Tag, ref.Hash(), &git.CreateTagOptions{
+		Tagger:  tagger,
+		Message: params.Message,
+	})
+	if err != nil {
+		log.Printf("[TRACE] error after repo.CreateTag(%v, %v)", params.Tag, ref.Hash())
+		return err
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", tagRefName, tagRefName))
+
+	err = repo.Push(&git.PushOptions{
+		RemoteName: params.RemoteName,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       getAuth(params.GitlabToken),
+	})
+	if err != nil {
+		log.Printf("[TRACE] error after repo.Push(%v)", refSpec)
+		// Don't leave the tag which is not pushed
+		if err := repo.DeleteTag(params.Tag); err != nil {
+			log.Printf("[WARNING] cannot delete tag %s: %v", params.Tag, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Tagger from parameters or from git config
+func getTagger(repo *git.Repository, name string, email string) (*object.Signature, error) {
+	if name == "" || email == "" {
+		cfg, err := repo.ConfigScoped(config.GlobalScope)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = cfg.User.Name
+		}
+		if email == "" {
+			email = cfg.User.Email
+		}
+	}
+
+	if name == "" || email == "" {
+		return nil, errors.New("tagger name and email are not configured")
+	}
+
+	return &object.Signature{
+		Name:  name,
+		Email: email,
+		When:  time.Now(),
+	}, nil
+}
+
+type CommitMessagesParams struct {
+	RepositoryPath string
+	Tag            string
+}
+
+// List messages of commits reachable from HEAD but not from the tag. All
+// commits are listed if there is no tag.
+func CommitMessages(params CommitMessagesParams) ([]string, error) {
+	log.Printf(
+		"[TRACE] CommitMessages(RepositoryPath=%v, Tag=%v)",
+		params.RepositoryPath,
+		params.Tag,
+	)
+
+	repo, err := git.PlainOpen(params.RepositoryPath)
+	if err != nil {
+		log.Printf("[TRACE] error after git.PlainOpen(%v)", params.RepositoryPath)
+		return nil, err
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		log.Printf("[TRACE] error after repo.Head()")
+		return nil, err
+	}
+
+	headObj, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		log.Printf("[TRACE] error after repo.CommitObject(ref.Hash())")
+		return nil, err
+	}
+
+	released := map[plumbing.Hash]int{}
+
+	if params.Tag != "" {
+		tagRef, err := repo.Tag(params.Tag)
+		if err != nil {
+			log.Printf("[TRACE] error after repo.Tag(%v)", params.Tag)
+			return nil, err
+		}
+
+		tagObj, _, _, err := resolveTag(repo, tagRef)
+		if err != nil {
+			log.Printf("[TRACE] error after resolveTag(repo, %v)", tagRef)
+			return nil, err
+		}
+
+		released, err = findReachableCommits(repo, tagObj)
+		if err != nil {
+			log.Printf("[TRACE] error after findReachableCommits(repo, %v)", tagObj)
+			return nil, err
+		}
+	}
+
+	messages := []string{}
+
+	if _, ok := released[headObj.Hash]; ok {
+		return messages, nil
+	}
+
+	seen := map[plumbing.Hash]bool{headObj.Hash: true}
+	queue := []*object.Commit{headObj}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		messages = append(messages, current.Message)
+
+		for _, parentHash := range current.ParentHashes {
+			if _, ok := released[parentHash]; ok || seen[parentHash] {
+				continue
+			}
+			seen[parentHash] = true
+			parentObj, err := repo.CommitObject(parentHash)
+			if err == plumbing.ErrObjectNotFound {
+				// shallow clone
+				log.Printf("[DEBUG] parent commit %s not found, history is truncated", parentHash)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, parentObj)
+		}
 	}
 
-	return mostRecentTag, nil
+	return messages, nil
 }
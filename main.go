@@ -3,57 +3,140 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/hashicorp/logutils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	gitlab "github.com/xanzy/go-gitlab"
 
+	"github.com/dex4er/gitlab-ci-semver-labels/components"
+	"github.com/dex4er/gitlab-ci-semver-labels/conventional"
 	"github.com/dex4er/gitlab-ci-semver-labels/git"
+	"github.com/dex4er/gitlab-ci-semver-labels/output"
+	"github.com/dex4er/gitlab-ci-semver-labels/rules"
 	"github.com/dex4er/gitlab-ci-semver-labels/semver"
 )
 
 var version = "dev"
 
+const versionLabelColor = "#428BCA"
+
+// Sources of the bump level
+const (
+	sourceLabels              = "labels"
+	sourceConventionalCommits = "conventional-commits"
+	sourceMergeRequests       = "merge-requests"
+)
+
+// Policies for more than 1 semver label
+const (
+	labelConflictError   = "error"
+	labelConflictHighest = "highest"
+	labelConflictLowest  = "lowest"
+)
+
+// Reasons why there is no new version
+const (
+	reasonMergeRequestNotFound = "merge-request-not-found"
+	reasonNoLabelMatched       = "no-label-matched"
+	reasonNoTagFound           = "no-tag-found"
+	reasonNotTouched           = "component-not-touched"
+	reasonSkipped              = "skipped-by-label"
+)
+
+// There is no tag to bump, ie. for the new component
+var errNoTag = errors.New("no tag found")
+
+// Add the hint to the missing tag error if the history is truncated in the
+// shallow clone
+func noTagHint(params handleSemverLabelsParams, err error) error {
+	if !errors.Is(err, errNoTag) {
+		return err
+	}
+	shallow, shallowErr := git.IsShallow(params.WorkTree)
+	if shallowErr != nil || !shallow {
+		return err
+	}
+	return fmt.Errorf("%w: history is truncated, fetch full history, ie. with GIT_DEPTH: 0", err)
+}
+
+// Hidden marker to find the note created previously
+const mergeRequestNoteMarker = "<!-- gitlab-ci-semver-labels -->"
+
+const noNewVersionNote = "Merging this will not release a new version."
+
 type handleSemverLabelsParams struct {
-	BumpInitial           bool
-	BumpPatch             bool
-	BumpMinor             bool
-	BumpMajor             bool
-	CommitMessageRegexp   string
-	Current               bool
-	DotenvFile            string
-	DotenvVar             string
-	Fail                  bool
-	FetchTags             bool
-	GitlabTokenEnv        string
-	GitlabUrl             string
-	InitialLabelRegexp    string
-	InitialVersion        string
-	MajorLabelRegexp      string
-	MinorLabelRegexp      string
-	PatchLabelRegexp      string
-	Prerelease            bool
-	PrereleaseLabelRegexp string
-	Project               string
-	RemoteName            string
-	WorkTree              string
+	AddVersionLabel         string
+	AllTags                 bool
+	BuildMetadata           string
+	BumpInitial             bool
+	BumpPatch               bool
+	BumpMinor               bool
+	BumpMajor               bool
+	BumpRelease             bool
+	CommitMessageRegexp     string
+	Components              []components.Component
+	CreateTag               bool
+	Current                 bool
+	DotenvFile              string
+	DotenvMode              string
+	DotenvPrefix            string
+	DotenvRich              bool
+	DotenvTagVar            string
+	DotenvVar               string
+	Fail                    bool
+	FetchTags               bool
+	GitlabTokenEnv          string
+	GitlabUrl               string
+	InitialLabelRegexp      string
+	InitialVersion          string
+	LabelConflict           string
+	MajorLabelRegexp        string
+	MergeRequestNote        string
+	MergeRequests           bool
+	MinorLabelRegexp        string
+	OutputFormat            string
+	PatchLabelRegexp        string
+	Prerelease              bool
+	PrereleaseID            string
+	PrereleaseIDLabelRegexp string
+	PrereleaseLabelRegexp   string
+	ReleaseLabelRegexp      string
+	Project                 string
+	ReleaseAssetLinks       []string
+	ReleaseDescription      string
+	ReleaseName             string
+	ReleaseRef              string
+	RemoteName              string
+	Rules                   []rules.Rule
+	SkipLabelRegexp         string
+	Sources                 []string
+	TagMessage              string
+	TagOrder                string
+	TagPrefix               string
+	WorkTree                string
 }
 
 func main() {
 	logLevel := os.Getenv("GITLAB_CI_SEMVER_LABELS_LOG")
 	if logLevel == "" {
-		logLevel = "ERROR"
+		logLevel = "NOTICE"
 	}
 
 	filter := &logutils.LevelFilter{
-		Levels:   []logutils.LogLevel{"TRACE", "DEBUG", "WARNING", "ERROR"},
+		Levels:   []logutils.LogLevel{"TRACE", "DEBUG", "WARNING", "NOTICE", "ERROR"},
 		MinLevel: logutils.LogLevel(logLevel),
 		Writer:   os.Stderr,
 	}
@@ -99,23 +182,50 @@ func main() {
 		},
 	}
 
+	// the `--output` option is an alias for the `--output-format` option
+	rootCmd.SetGlobalNormalizationFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "output" {
+			name = "output-format"
+		}
+		return pflag.NormalizedName(name)
+	})
+	viper.RegisterAlias("output", "output-format")
+
+	rootCmd.PersistentFlags().BoolP("all-tags", "A", false, "consider all tags, not only reachable from HEAD")
+	rootCmd.PersistentFlags().String("build-metadata", "", "build metadata from `TEMPLATE` appended to the version, ie. pipeline.$CI_PIPELINE_IID")
 	rootCmd.PersistentFlags().StringP("dotenv-file", "d", "", "write dotenv format to `FILE`")
+	rootCmd.PersistentFlags().String("dotenv-mode", output.DotenvOverwrite, "`MODE` of writing to dotenv file: overwrite, append or merge")
+	rootCmd.PersistentFlags().String("dotenv-prefix", "", "`PREFIX` for variables in dotenv file")
+	rootCmd.PersistentFlags().Bool("dotenv-rich", false, "write parts of the version, the previous version, the bump level and the merge request to dotenv file")
+	rootCmd.PersistentFlags().String("dotenv-tag-var", "", "variable `NAME` for the tag name in dotenv file, ie. TAG")
 	rootCmd.PersistentFlags().StringP("dotenv-var", "D", "VERSION", "variable `NAME` in dotenv file")
 	rootCmd.PersistentFlags().BoolP("fetch-tags", "T", true, "fetch tags from git repo")
 	rootCmd.PersistentFlags().StringP("gitlab-token-env", "t", "GITLAB_TOKEN", "name for environment `VAR` with Gitlab token")
 	rootCmd.PersistentFlags().StringP("gitlab-url", "g", "https://gitlab.com", "`URL` of the Gitlab instance")
+	rootCmd.PersistentFlags().String("output-format", output.Text, "`FORMAT` of the output: text, dotenv, shell, json, yaml or github-output")
 	rootCmd.PersistentFlags().StringP("project", "p", "", "`PROJECT` id or name (default $CI_PROJECT_ID)")
 	rootCmd.PersistentFlags().StringP("remote-name", "r", "origin", "`NAME` of git remote")
+	rootCmd.PersistentFlags().String("tag-order", git.TagOrderSemver, "`ORDER` to choose the last tag: semver, time or topology")
+	rootCmd.PersistentFlags().String("tag-prefix", "", "`PREFIX` of tags, ie. for monorepo components")
 	rootCmd.PersistentFlags().StringP("work-tree", "C", ".", "`DIR` to be used for git operations")
 
 	for _, flag := range []string{
+		"all-tags",
+		"build-metadata",
 		"dotenv-file",
+		"dotenv-mode",
+		"dotenv-prefix",
+		"dotenv-rich",
+		"dotenv-tag-var",
 		"dotenv-var",
 		"fetch-tags",
 		"gitlab-token-env",
 		"gitlab-url",
+		"output-format",
 		"project",
 		"remote-name",
+		"tag-order",
+		"tag-prefix",
 		"work-tree",
 	} {
 		if err := viper.BindPFlag(flag, rootCmd.PersistentFlags().Lookup(flag)); err != nil {
@@ -137,8 +247,16 @@ func main() {
 		Use:   "bump",
 		Short: "Bump version",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			params.AddVersionLabel = viper.GetString("add-version-label")
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
 			params.CommitMessageRegexp = viper.GetString("commit-message-regexp")
+			params.CreateTag = viper.GetBool("create-tag")
 			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
 			params.DotenvVar = viper.GetString("dotenv-var")
 			params.Fail = viper.GetBool("fail")
 			params.FetchTags = viper.GetBool("fetch-tags")
@@ -146,41 +264,70 @@ func main() {
 			params.GitlabUrl = viper.GetString("gitlab-url")
 			params.InitialLabelRegexp = viper.GetString("initial-label-regexp")
 			params.InitialVersion = viper.GetString("initial-version")
+			params.LabelConflict = viper.GetString("label-conflict")
 			params.MajorLabelRegexp = viper.GetString("major-label-regexp")
+			params.MergeRequestNote = viper.GetString("merge-request-note")
 			params.MinorLabelRegexp = viper.GetString("minor-label-regexp")
+			params.OutputFormat = viper.GetString("output-format")
 			params.PatchLabelRegexp = viper.GetString("patch-label-regexp")
+			params.Prerelease = viper.GetBool("prerelease")
+			params.PrereleaseID = viper.GetString("prerelease-id")
+			params.PrereleaseIDLabelRegexp = viper.GetString("prerelease-id-label-regexp")
 			params.PrereleaseLabelRegexp = viper.GetString("prerelease-label-regexp")
+			params.ReleaseLabelRegexp = viper.GetString("release-label-regexp")
 			params.RemoteName = viper.GetString("remote-name")
 			params.Project = viper.GetString("project")
+			params.SkipLabelRegexp = viper.GetString("skip-label-regexp")
+			params.Sources = viper.GetStringSlice("source")
+			params.TagMessage = viper.GetString("tag-message")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
 			params.WorkTree = viper.GetString("work-tree")
 
 			if err := handleSemverLabels(params); err != nil {
-				fmt.Println("Error:", err)
+				printError(params, err)
 				os.Exit(2)
 			}
 			return nil
 		},
 	}
 
+	bumpCmd.Flags().String("add-version-label", "", "add label from `TEMPLATE` to the merge request, ie. v::$VERSION")
 	bumpCmd.Flags().String("commit-message-regexp", `(?s)(?:^|\n)See merge request (?:\w[\w.+/-]*)?!(\d+)`, "`REGEXP` for commit message after merged MR")
 	bumpCmd.Flags().BoolP("fail", "f", false, "fail if labels are not matched")
 	bumpCmd.Flags().String("initial-label-regexp", "(?i)initial.release|semver(.|::)initial", "`REGEXP` for initial release label")
 	bumpCmd.Flags().StringP("initial-version", "V", "0.0.0", "initial `VERSION` for initial release")
+	bumpCmd.Flags().String("label-conflict", labelConflictError, "`POLICY` for more than 1 semver label: error, highest or lowest")
 	bumpCmd.Flags().String("major-label-regexp", "(?i)(major|breaking).release|semver(.|::)(major|breaking)", "`REGEXP` for major (breaking) release label")
+	bumpCmd.Flags().String("merge-request-note", "", "upsert note from `TEMPLATE` on the merge request in merge request pipeline")
 	bumpCmd.Flags().String("minor-label-regexp", "(?i)(minor|feature).release|semver(.|::)(minor|feature)", "`REGEXP` for minor (feature) release label")
 	bumpCmd.Flags().String("patch-label-regexp", "(?i)(patch|fix).release|semver(.|::)(patch|fix)", "`REGEXP` for patch (fix) release label")
+	bumpCmd.PersistentFlags().Bool("create-tag", false, "create and push the tag for the new version")
 	bumpCmd.PersistentFlags().BoolP("prerelease", "P", false, "bump version as prerelease")
+	bumpCmd.PersistentFlags().String("prerelease-id", "", "bump version as prerelease with `ID`, ie. alpha, beta or rc")
+	bumpCmd.Flags().String("prerelease-id-label-regexp", "semver(?:.|::)(alpha|beta|rc)", "`REGEXP` for prerelease label with the prerelease id as the first submatch")
 	bumpCmd.Flags().String("prerelease-label-regexp", "(?i)pre.?release", "`REGEXP` for prerelease label")
+	bumpCmd.Flags().String("release-label-regexp", "(?i)final.release|semver(.|::)(release|final)", "`REGEXP` for label which finalizes the prerelease")
+	bumpCmd.Flags().String("skip-label-regexp", "(?i)(skip|no).release|semver(.|::)(none|skip)", "`REGEXP` for label which skips the release")
+	bumpCmd.Flags().StringSlice("source", []string{sourceLabels}, "`SOURCES` of the bump level in order: labels, merge-requests, conventional-commits")
+	bumpCmd.PersistentFlags().String("tag-message", "Release $TAG", "`TEMPLATE` for the message of the created tag")
 
 	for _, flag := range []string{
+		"add-version-label",
 		"commit-message-regexp",
 		"fail",
 		"initial-label-regexp",
 		"initial-version",
+		"label-conflict",
 		"major-label-regexp",
+		"merge-request-note",
 		"minor-label-regexp",
 		"patch-label-regexp",
+		"prerelease-id-label-regexp",
 		"prerelease-label-regexp",
+		"release-label-regexp",
+		"skip-label-regexp",
+		"source",
 	} {
 		if err := viper.BindPFlag(flag, bumpCmd.Flags().Lookup(flag)); err != nil {
 			fmt.Println("Error: incorrect config file:", err)
@@ -189,7 +336,10 @@ func main() {
 	}
 
 	for _, flag := range []string{
+		"create-tag",
 		"prerelease",
+		"prerelease-id",
+		"tag-message",
 	} {
 		if err := viper.BindPFlag(flag, bumpCmd.PersistentFlags().Lookup(flag)); err != nil {
 			fmt.Println("Error: incorrect config file:", err)
@@ -203,18 +353,31 @@ func main() {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			params.BumpInitial = true
 
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
+			params.CreateTag = viper.GetBool("create-tag")
 			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
 			params.DotenvVar = viper.GetString("dotenv-var")
 			params.FetchTags = viper.GetBool("fetch-tags")
 			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
 			params.GitlabUrl = viper.GetString("gitlab-url")
 			params.InitialVersion = viper.GetString("initial-version")
+			params.OutputFormat = viper.GetString("output-format")
 			params.RemoteName = viper.GetString("remote-name")
+			params.Prerelease = viper.GetBool("prerelease")
+			params.PrereleaseID = viper.GetString("prerelease-id")
 			params.Project = viper.GetString("project")
+			params.TagMessage = viper.GetString("tag-message")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
 			params.WorkTree = viper.GetString("work-tree")
 
 			if err := handleSemverLabels(params); err != nil {
-				fmt.Println("Error:", err)
+				printError(params, err)
 				os.Exit(2)
 			}
 			return nil
@@ -240,18 +403,30 @@ func main() {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			params.BumpMajor = true
 
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
+			params.CreateTag = viper.GetBool("create-tag")
 			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
 			params.DotenvVar = viper.GetString("dotenv-var")
 			params.FetchTags = viper.GetBool("fetch-tags")
 			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
 			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.OutputFormat = viper.GetString("output-format")
 			params.RemoteName = viper.GetString("remote-name")
 			params.Prerelease = viper.GetBool("prerelease")
+			params.PrereleaseID = viper.GetString("prerelease-id")
 			params.Project = viper.GetString("project")
+			params.TagMessage = viper.GetString("tag-message")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
 			params.WorkTree = viper.GetString("work-tree")
 
 			if err := handleSemverLabels(params); err != nil {
-				fmt.Println("Error:", err)
+				printError(params, err)
 				os.Exit(2)
 			}
 			return nil
@@ -266,18 +441,30 @@ func main() {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			params.BumpMinor = true
 
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
+			params.CreateTag = viper.GetBool("create-tag")
 			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
 			params.DotenvVar = viper.GetString("dotenv-var")
 			params.FetchTags = viper.GetBool("fetch-tags")
 			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
 			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.OutputFormat = viper.GetString("output-format")
 			params.RemoteName = viper.GetString("remote-name")
 			params.Prerelease = viper.GetBool("prerelease")
+			params.PrereleaseID = viper.GetString("prerelease-id")
 			params.Project = viper.GetString("project")
+			params.TagMessage = viper.GetString("tag-message")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
 			params.WorkTree = viper.GetString("work-tree")
 
 			if err := handleSemverLabels(params); err != nil {
-				fmt.Println("Error:", err)
+				printError(params, err)
 				os.Exit(2)
 			}
 			return nil
@@ -292,18 +479,30 @@ func main() {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			params.BumpPatch = true
 
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
+			params.CreateTag = viper.GetBool("create-tag")
 			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
 			params.DotenvVar = viper.GetString("dotenv-var")
 			params.FetchTags = viper.GetBool("fetch-tags")
 			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
 			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.OutputFormat = viper.GetString("output-format")
 			params.RemoteName = viper.GetString("remote-name")
 			params.Prerelease = viper.GetBool("prerelease")
+			params.PrereleaseID = viper.GetString("prerelease-id")
 			params.Project = viper.GetString("project")
+			params.TagMessage = viper.GetString("tag-message")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
 			params.WorkTree = viper.GetString("work-tree")
 
 			if err := handleSemverLabels(params); err != nil {
-				fmt.Println("Error:", err)
+				printError(params, err)
 				os.Exit(2)
 			}
 			return nil
@@ -312,6 +511,42 @@ func main() {
 
 	bumpCmd.AddCommand(bumpPatchCmd)
 
+	bumpReleaseCmd := &cobra.Command{
+		Use:   "release",
+		Short: "Finalize the prerelease version without checking labels",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params.BumpRelease = true
+
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
+			params.CreateTag = viper.GetBool("create-tag")
+			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
+			params.DotenvVar = viper.GetString("dotenv-var")
+			params.FetchTags = viper.GetBool("fetch-tags")
+			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
+			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.OutputFormat = viper.GetString("output-format")
+			params.RemoteName = viper.GetString("remote-name")
+			params.Project = viper.GetString("project")
+			params.TagMessage = viper.GetString("tag-message")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
+			params.WorkTree = viper.GetString("work-tree")
+
+			if err := handleSemverLabels(params); err != nil {
+				printError(params, err)
+				os.Exit(2)
+			}
+			return nil
+		},
+	}
+
+	bumpCmd.AddCommand(bumpReleaseCmd)
+
 	rootCmd.AddCommand(bumpCmd)
 
 	currentCmd := &cobra.Command{
@@ -320,16 +555,25 @@ func main() {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			params.Current = true
 
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
 			params.DotenvFile = viper.GetString("dotenv-file")
+			params.DotenvMode = viper.GetString("dotenv-mode")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvRich = viper.GetBool("dotenv-rich")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
 			params.DotenvVar = viper.GetString("dotenv-var")
 			params.FetchTags = viper.GetBool("fetch-tags")
 			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
 			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.OutputFormat = viper.GetString("output-format")
 			params.RemoteName = viper.GetString("remote-name")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
 			params.WorkTree = viper.GetString("work-tree")
 
 			if err := handleSemverLabels(params); err != nil {
-				fmt.Println("Error:", err)
+				printError(params, err)
 				os.Exit(2)
 			}
 			return nil
@@ -338,6 +582,172 @@ func main() {
 
 	rootCmd.AddCommand(currentCmd)
 
+	explainCmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Explain how the version is bumped",
+		Long:  "Explain how the version is bumped: tags, the merge request, labels and the result, without writing anything",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params.AllTags = viper.GetBool("all-tags")
+			params.BuildMetadata = viper.GetString("build-metadata")
+			params.CommitMessageRegexp = viper.GetString("commit-message-regexp")
+			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
+			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.InitialLabelRegexp = viper.GetString("initial-label-regexp")
+			params.InitialVersion = viper.GetString("initial-version")
+			params.LabelConflict = viper.GetString("label-conflict")
+			params.MajorLabelRegexp = viper.GetString("major-label-regexp")
+			params.MinorLabelRegexp = viper.GetString("minor-label-regexp")
+			params.PatchLabelRegexp = viper.GetString("patch-label-regexp")
+			params.Prerelease = viper.GetBool("prerelease")
+			params.PrereleaseID = viper.GetString("prerelease-id")
+			params.PrereleaseIDLabelRegexp = viper.GetString("prerelease-id-label-regexp")
+			params.PrereleaseLabelRegexp = viper.GetString("prerelease-label-regexp")
+			params.ReleaseLabelRegexp = viper.GetString("release-label-regexp")
+			params.RemoteName = viper.GetString("remote-name")
+			params.Project = viper.GetString("project")
+			params.SkipLabelRegexp = viper.GetString("skip-label-regexp")
+			params.Sources = viper.GetStringSlice("source")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
+			params.WorkTree = viper.GetString("work-tree")
+
+			// explain only reads: tags are not fetched and the reason is
+			// reported instead of the failure
+			params.Fail = false
+			params.FetchTags = false
+
+			if err := handleExplain(params); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(2)
+			}
+			return nil
+		},
+	}
+
+	// the same options as for the bump command
+	explainCmd.Flags().AddFlagSet(bumpCmd.Flags())
+	explainCmd.Flags().AddFlag(bumpCmd.PersistentFlags().Lookup("prerelease"))
+	explainCmd.Flags().AddFlag(bumpCmd.PersistentFlags().Lookup("prerelease-id"))
+
+	rootCmd.AddCommand(explainCmd)
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show all semver tags",
+		Long:  "Show all semver tags sorted by the precedence with the commit, the tagger and the merge request",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params.FetchTags = viper.GetBool("fetch-tags")
+			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
+			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.MergeRequests = viper.GetBool("merge-requests")
+			params.OutputFormat = viper.GetString("output-format")
+			params.Project = viper.GetString("project")
+			params.RemoteName = viper.GetString("remote-name")
+			params.TagPrefix = viper.GetString("tag-prefix")
+			params.WorkTree = viper.GetString("work-tree")
+
+			if err := handleHistory(params); err != nil {
+				printError(params, err)
+				os.Exit(2)
+			}
+			return nil
+		},
+	}
+
+	historyCmd.Flags().Bool("merge-requests", false, "find merge requests for tags with Gitlab API")
+
+	if err := viper.BindPFlag("merge-requests", historyCmd.Flags().Lookup("merge-requests")); err != nil {
+		fmt.Println("Error: incorrect config file:", err)
+		os.Exit(1)
+	}
+
+	rootCmd.AddCommand(historyCmd)
+
+	nextCmd := &cobra.Command{
+		Use:   "next",
+		Short: "Show current and all possible next versions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params.AllTags = viper.GetBool("all-tags")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvVar = viper.GetString("dotenv-var")
+			params.FetchTags = viper.GetBool("fetch-tags")
+			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
+			params.OutputFormat = viper.GetString("output-format")
+			params.PrereleaseID = viper.GetString("prerelease-id")
+			params.RemoteName = viper.GetString("remote-name")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
+			params.WorkTree = viper.GetString("work-tree")
+
+			if err := handleNext(params); err != nil {
+				printError(params, err)
+				os.Exit(2)
+			}
+			return nil
+		},
+	}
+
+	nextCmd.Flags().AddFlag(bumpCmd.PersistentFlags().Lookup("prerelease-id"))
+
+	rootCmd.AddCommand(nextCmd)
+
+	releaseCmd := &cobra.Command{
+		Use:   "release [VERSION]",
+		Short: "Create Gitlab release for version",
+		Long:  "Create Gitlab release for the version from the argument or from the variable from dotenv file",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params.AllTags = viper.GetBool("all-tags")
+			params.DotenvPrefix = viper.GetString("dotenv-prefix")
+			params.DotenvTagVar = viper.GetString("dotenv-tag-var")
+			params.DotenvVar = viper.GetString("dotenv-var")
+			params.FetchTags = viper.GetBool("fetch-tags")
+			params.GitlabTokenEnv = viper.GetString("gitlab-token-env")
+			params.GitlabUrl = viper.GetString("gitlab-url")
+			params.Project = viper.GetString("project")
+			params.ReleaseAssetLinks = viper.GetStringSlice("release-asset-link")
+			params.ReleaseDescription = viper.GetString("release-description")
+			params.ReleaseName = viper.GetString("release-name")
+			params.ReleaseRef = viper.GetString("release-ref")
+			params.RemoteName = viper.GetString("remote-name")
+			params.TagOrder = viper.GetString("tag-order")
+			params.TagPrefix = viper.GetString("tag-prefix")
+			params.WorkTree = viper.GetString("work-tree")
+
+			var err error
+			if len(args) > 0 {
+				err = handleRelease(args[0], "", params)
+			} else {
+				err = handleDotenvRelease(params)
+			}
+
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(2)
+			}
+			return nil
+		},
+	}
+
+	releaseCmd.Flags().StringArray("release-asset-link", []string{}, "asset link as `NAME=URL` (can be repeated)")
+	releaseCmd.Flags().String("release-description", "Automatic release by gitlab-ci-semver-labels", "`TEMPLATE` for the description of the release")
+	releaseCmd.Flags().String("release-name", "$TAG", "`TEMPLATE` for the name of the release")
+	releaseCmd.Flags().String("release-ref", "", "`REF` for the tag of the release (default $CI_COMMIT_SHA)")
+
+	for _, flag := range []string{
+		"release-asset-link",
+		"release-description",
+		"release-name",
+		"release-ref",
+	} {
+		if err := viper.BindPFlag(flag, releaseCmd.Flags().Lookup(flag)); err != nil {
+			fmt.Println("Error: incorrect config file:", err)
+			os.Exit(1)
+		}
+	}
+
+	rootCmd.AddCommand(releaseCmd)
+
 	if err := viper.BindEnv("gitlab-url", "CI_SERVER_URL"); err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
@@ -348,240 +758,1857 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := viper.BindEnv("release-ref", "CI_COMMIT_SHA"); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	params.Components, err = loadComponents()
+	if err != nil {
+		fmt.Println("Error: incorrect config file:", err)
+		os.Exit(1)
+	}
+
+	if err := viper.UnmarshalKey("rules", &params.Rules); err != nil {
+		fmt.Println("Error: incorrect config file:", err)
+		os.Exit(1)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
-func printVersion(ver string, dotenvFile string, dotenvVar string) error {
+// Version printed to the output and written to the dotenv file
+type printedVersion struct {
+	Version string
+	// Variables for dotenv file
+	Vars []output.Var
+}
+
+func printVersions(params handleSemverLabelsParams, versions []printedVersion, report any) error {
+	dotenvFile := params.DotenvFile
 	if dotenvFile != "" {
-		file, err := os.Create(dotenvFile)
-		if err != nil {
-			return fmt.Errorf("cannot create file: %w", err)
-		}
-		defer file.Close()
-		_, err = file.WriteString(fmt.Sprintf("%s=%s\n", dotenvVar, ver))
-		if err != nil {
+		if err := output.WriteDotenvFile(dotenvFile, params.DotenvMode, versionsVars(versions)); err != nil {
 			return fmt.Errorf("cannot write to file: %w", err)
 		}
 		log.Println("[DEBUG] Written to file:", dotenvFile)
 	}
-	_, err := fmt.Println(ver)
-	return err
-}
-
-func handleSemverLabels(params handleSemverLabelsParams) error {
-	gitlabToken := os.Getenv(params.GitlabTokenEnv)
 
-	log.Println("[DEBUG] Find last tag for remote:", params.RemoteName)
-	if params.FetchTags {
-		log.Println("[DEBUG] Fetch tags")
+	if output.IsDocument(params.OutputFormat) {
+		return printReport(params, report)
 	}
 
-	tag, err := git.FindLastTag(git.FindLastTagParams{
-		RepositoryPath: params.WorkTree,
-		RemoteName:     params.RemoteName,
-		GitlabToken:    gitlabToken,
-		FetchTags:      params.FetchTags,
-	})
+	if writer, ok := output.VarsWriters[params.OutputFormat]; ok {
+		return writer(os.Stdout, versionsVars(versions))
+	}
 
-	if err != nil {
-		return fmt.Errorf("cannot find the last git tag: %w", err)
+	for _, v := range versions {
+		if _, err := fmt.Println(v.Version); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	log.Printf("[DEBUG] Most recent tag: %v", tag)
+func versionsVars(versions []printedVersion) []output.Var {
+	vars := []output.Var{}
+	for _, v := range versions {
+		vars = append(vars, v.Vars...)
+	}
+	return vars
+}
 
-	if params.Current {
-		ver, err := semver.Current(tag)
-		if err != nil {
-			return fmt.Errorf("current tag (%s) is not semver: %w", tag, err)
+// Version with variables for dotenv file. The rich output adds parts of the
+// version, the previous version and the decision about the bump.
+func newPrintedVersion(params handleSemverLabelsParams, result bumpResult, lastTag string, namePrefix string, component string) (printedVersion, error) {
+	varName := func(name string) string {
+		name = params.DotenvPrefix + name
+		if component != "" {
+			return componentDotenvVar(name, component)
 		}
-		return printVersion(ver, params.DotenvFile, params.DotenvVar)
+		return name
 	}
 
-	if params.BumpInitial {
-		if tag != "" {
-			return errors.New("semver is already initialized")
-		}
+	ver := result.Version
 
-		ver := params.InitialVersion
+	printed := printedVersion{}
+	if ver != "" {
+		printed.Version = versionPrefix(lastTag, params.TagPrefix) + ver
+	}
 
-		if params.Prerelease {
-			ver, err = semver.BumpPrerelease(ver)
+	printed.Vars = append(printed.Vars, output.Var{Name: varName(params.DotenvVar), Value: printed.Version})
+
+	if params.DotenvTagVar != "" {
+		tag := ""
+		if ver != "" {
+			tag = tagName(namePrefix, ver)
 		}
+		printed.Vars = append(printed.Vars, output.Var{Name: varName(params.DotenvTagVar), Value: tag})
+	}
+
+	if !params.DotenvRich {
+		return printed, nil
+	}
+
+	var major, minor, patch, prerelease string
+	if ver != "" {
+		parts, err := semver.Parse(ver)
 		if err != nil {
-			return fmt.Errorf("cannot bump tag: %w", err)
+			return printedVersion{}, err
 		}
+		major = strconv.FormatUint(parts.Major, 10)
+		minor = strconv.FormatUint(parts.Minor, 10)
+		patch = strconv.FormatUint(parts.Patch, 10)
+		prerelease = parts.Prerelease
+	}
 
-		return printVersion(ver, params.DotenvFile, params.DotenvVar)
+	previous, err := previousVersion(lastTag, params.TagPrefix)
+	if err != nil {
+		return printedVersion{}, err
 	}
 
-	if params.BumpPatch || params.BumpMinor || params.BumpMajor {
-		if tag == "" {
-			return errors.New("no tag found")
-		}
+	mergeRequest := ""
+	if result.MergeRequest != 0 {
+		mergeRequest = strconv.Itoa(result.MergeRequest)
+	}
 
-		ver := tag
-		var err error
+	printed.Vars = append(printed.Vars,
+		output.Var{Name: varName(params.DotenvVar + "_MAJOR"), Value: major},
+		output.Var{Name: varName(params.DotenvVar + "_MINOR"), Value: minor},
+		output.Var{Name: varName(params.DotenvVar + "_PATCH"), Value: patch},
+		output.Var{Name: varName(params.DotenvVar + "_PRERELEASE"), Value: prerelease},
+		output.Var{Name: varName("PREVIOUS_" + params.DotenvVar), Value: previous},
+		output.Var{Name: varName("BUMP_LEVEL"), Value: result.Level},
+		output.Var{Name: varName("MERGE_REQUEST_IID"), Value: mergeRequest},
+	)
+
+	return printed, nil
+}
 
-		if params.BumpPatch {
-			ver, err = semver.BumpPatch(ver, params.Prerelease)
-		}
+// Version from the last tag with the prefix as printed
+func previousVersion(lastTag string, tagPrefix string) (string, error) {
+	if lastTag == "" {
+		return "", nil
+	}
+	ver, err := semver.Current(strings.TrimPrefix(lastTag, tagPrefix))
+	if err != nil {
+		return "", fmt.Errorf("last tag (%s) is not semver: %w", lastTag, err)
+	}
+	return versionPrefix(lastTag, tagPrefix) + ver, nil
+}
 
-		if params.BumpMinor {
-			ver, err = semver.BumpMinor(ver, params.Prerelease)
-		}
+// Report about the version for JSON output
+type versionReport struct {
+	Component       string              `json:"component,omitempty" yaml:"component,omitempty"`
+	PreviousTag     string              `json:"previous_tag" yaml:"previous_tag"`
+	PreviousVersion string              `json:"previous_version" yaml:"previous_version"`
+	Version         string              `json:"version" yaml:"version"`
+	Tag             string              `json:"tag" yaml:"tag"`
+	BumpLevel       string              `json:"bump_level" yaml:"bump_level"`
+	Source          string              `json:"source" yaml:"source"`
+	Label           string              `json:"label" yaml:"label"`
+	MatchedLabels   []string            `json:"matched_labels" yaml:"matched_labels"`
+	Commit          string              `json:"commit" yaml:"commit"`
+	MergeRequest    *mergeRequestReport `json:"merge_request" yaml:"merge_request"`
+	Reason          string              `json:"reason" yaml:"reason"`
+}
 
-		if params.BumpMajor {
-			ver, err = semver.BumpMajor(ver, params.Prerelease)
-		}
+type mergeRequestReport struct {
+	Project string `json:"project" yaml:"project"`
+	IID     int    `json:"iid" yaml:"iid"`
+}
 
-		if err != nil {
-			return fmt.Errorf("cannot bump tag: %w", err)
-		}
+// Report about components for JSON output
+type componentsReport struct {
+	Components []versionReport `json:"components" yaml:"components"`
+	Reason     string          `json:"reason" yaml:"reason"`
+}
 
-		return printVersion(ver, params.DotenvFile, params.DotenvVar)
+func newVersionReport(params handleSemverLabelsParams, result bumpResult, lastTag string, namePrefix string, component string) (versionReport, error) {
+	previous, err := previousVersion(lastTag, params.TagPrefix)
+	if err != nil {
+		return versionReport{}, err
 	}
 
-	var labels gitlab.Labels
+	report := versionReport{
+		Component:       component,
+		PreviousTag:     lastTag,
+		PreviousVersion: previous,
+		BumpLevel:       result.Level,
+		Source:          result.Source,
+		Label:           result.Label,
+		MatchedLabels:   []string{},
+		Commit:          result.Commit,
+	}
 
-	mergeRequestLabels := os.Getenv("CI_MERGE_REQUEST_LABELS")
+	for _, match := range result.MatchedLabels {
+		report.MatchedLabels = append(report.MatchedLabels, match.Label)
+	}
 
-	if mergeRequestLabels != "" {
-		labels = strings.Split(mergeRequestLabels, ",")
-	} else {
-		commitMessage := os.Getenv("CI_COMMIT_MESSAGE")
+	if result.MergeRequest != 0 {
+		report.MergeRequest = &mergeRequestReport{Project: params.Project, IID: result.MergeRequest}
+	}
 
-		re_mr, err := regexp.Compile(params.CommitMessageRegexp)
-		if err != nil {
-			return err
-		}
-		matches := re_mr.FindStringSubmatch(commitMessage)
+	switch {
+	case result.Skipped:
+		report.Reason = reasonSkipped
+	case result.Version == "":
+		report.Reason = reasonNoLabelMatched
+	default:
+		report.Version = versionPrefix(lastTag, params.TagPrefix) + result.Version
+		report.Tag = tagName(namePrefix, result.Version)
+	}
 
-		if len(matches) < 2 {
-			log.Println("[WARNING] Merge request not found")
-			return nil
+	return report, nil
+}
+
+// Print the report as the document for JSON or YAML output only
+func printReport(params handleSemverLabelsParams, report any) error {
+	if !output.IsDocument(params.OutputFormat) {
+		return nil
+	}
+	return output.WriteDocument(os.Stdout, params.OutputFormat, report)
+}
+
+// Print the error. The error is printed as the document for JSON or YAML
+// output.
+func printError(params handleSemverLabelsParams, err error) {
+	if output.IsDocument(params.OutputFormat) {
+		if output.WriteDocument(os.Stdout, params.OutputFormat, map[string]string{"error": err.Error()}) == nil {
+			return
 		}
+	}
+	fmt.Println("Error:", err)
+}
 
-		mergeRequest, err := strconv.Atoi(matches[1])
-		if err != nil {
-			return fmt.Errorf("merge request number is invalid: %w", err)
+// Expand $VAR or ${VAR} in the template with given variables or environment
+// variables
+func expandTemplate(template string, vars map[string]string) string {
+	return os.Expand(template, func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
 		}
-		log.Println("[DEBUG] Merge request:", mergeRequest)
+		return os.Getenv(name)
+	})
+}
 
-		log.Println("[DEBUG] GitLab URL:", params.GitlabUrl)
-		gl, err := gitlab.NewClient(gitlabToken, gitlab.WithBaseURL(params.GitlabUrl))
-		if err != nil {
-			return fmt.Errorf("failed to create client: %w", err)
+// Prefix of the tag name for the version. The tag name mirrors the style of
+// the last tag, so `v` follows the tag prefix only if it follows it in the last
+// tag. Without any tag, tags start with `v` or with the tag prefix.
+func tagNamePrefix(lastTag string, tagPrefix string) string {
+	if lastTag == "" {
+		if tagPrefix != "" {
+			return tagPrefix
 		}
+		return "v"
+	}
+	if strings.HasPrefix(strings.TrimPrefix(lastTag, tagPrefix), "v") {
+		return tagPrefix + "v"
+	}
+	return tagPrefix
+}
 
-		log.Println("[DEBUG] Project:", params.Project)
-		opt := &gitlab.GetMergeRequestsOptions{}
-		mr, _, err := gl.MergeRequests.GetMergeRequest(params.Project, mergeRequest, opt)
+// Prefix of the printed version. The version is normalized without `v`, but
+// with the tag prefix it mirrors the style of the last tag.
+func versionPrefix(lastTag string, tagPrefix string) string {
+	if tagPrefix == "" {
+		return ""
+	}
+	return tagNamePrefix(lastTag, tagPrefix)
+}
 
-		if err != nil {
-			return fmt.Errorf("failed to get information about merge request: %w", err)
-		}
+// Name of the tag for the version. The build metadata is not a part of the tag
+// name.
+func tagName(namePrefix string, ver string) string {
+	ver, _, _ = strings.Cut(ver, "+")
+	return namePrefix + ver
+}
 
-		log.Println("[DEBUG] Found merge request:", mr)
+func createTag(params handleSemverLabelsParams, ver string, namePrefix string) error {
+	tag := tagName(namePrefix, ver)
 
-		labels = mr.Labels
-	}
+	message := expandTemplate(params.TagMessage, map[string]string{
+		"TAG":     tag,
+		"VERSION": ver,
+	})
 
-	log.Println("[DEBUG] Labels:", labels)
+	log.Println("[DEBUG] Create tag:", tag)
 
-	re_initial, err := regexp.Compile(params.InitialLabelRegexp)
+	err := git.CreateTag(git.CreateTagParams{
+		RepositoryPath: params.WorkTree,
+		RemoteName:     params.RemoteName,
+		GitlabToken:    os.Getenv(params.GitlabTokenEnv),
+		Tag:            tag,
+		Message:        message,
+		TaggerName:     os.Getenv("GITLAB_USER_NAME"),
+		TaggerEmail:    os.Getenv("GITLAB_USER_EMAIL"),
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot create tag %s: %w", tag, err)
 	}
-	re_major, err := regexp.Compile(params.MajorLabelRegexp)
-	if err != nil {
-		return err
+
+	return nil
+}
+
+// Add the label with the version to the merged merge request. The label is
+// created in the project if it is missing.
+func addVersionLabel(params handleSemverLabelsParams, mr *mergeRequestInfo, ver string, namePrefix string) error {
+	if mr == nil || mr.IID == 0 {
+		log.Println("[WARNING] Merge request is unknown. Label skipped.")
+		return nil
 	}
-	re_minor, err := regexp.Compile(params.MinorLabelRegexp)
-	if err != nil {
-		return err
+
+	// the merge request is not merged yet in the merge request pipeline
+	if mr.Pipeline {
+		log.Println("[WARNING] Merge request is not merged. Label skipped.")
+		return nil
 	}
-	re_patch, err := regexp.Compile(params.PatchLabelRegexp)
+
+	label := expandTemplate(params.AddVersionLabel, map[string]string{
+		"TAG":     tagName(namePrefix, ver),
+		"VERSION": ver,
+	})
+
+	gl, err := newGitlabClient(params)
 	if err != nil {
 		return err
 	}
-	re_prerelease, err := regexp.Compile(params.PrereleaseLabelRegexp)
+
+	_, resp, err := gl.Labels.GetLabel(params.Project, label)
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("failed to get label %s: %w", label, err)
+		}
+
+		log.Println("[DEBUG] Create label:", label)
+		_, _, err = gl.Labels.CreateLabel(params.Project, &gitlab.CreateLabelOptions{
+			Name:  gitlab.String(label),
+			Color: gitlab.String(versionLabelColor),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create label %s: %w", label, err)
+		}
+	}
+
+	log.Printf("[DEBUG] Add label %s to merge request %d", label, mr.IID)
+	_, _, err = gl.MergeRequests.UpdateMergeRequest(params.Project, mr.IID, &gitlab.UpdateMergeRequestOptions{
+		AddLabels: &gitlab.Labels{label},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add label to merge request: %w", err)
+	}
+
+	return nil
+}
+
+// Note about the version from the template
+func mergeRequestNote(params handleSemverLabelsParams, result bumpResult, namePrefix string, component string) string {
+	return expandTemplate(params.MergeRequestNote, map[string]string{
+		"BUMP_LEVEL": result.Level,
+		"COMMIT":     result.Commit,
+		"COMPONENT":  component,
+		"LABEL":      result.Label,
+		"SOURCE":     result.Source,
+		"TAG":        tagName(namePrefix, result.Version),
+		"VERSION":    result.Version,
+	})
+}
+
+// Create the note on the merge request or update the note created previously
+func upsertMergeRequestNote(params handleSemverLabelsParams, mr *mergeRequestInfo, notes []string) error {
+	if mr.IID == 0 {
+		log.Println("[WARNING] Merge request is unknown. Note skipped.")
+		return nil
+	}
+
+	if len(notes) == 0 {
+		notes = append(notes, noNewVersionNote)
+	}
+
+	body := strings.Join(notes, "\n\n") + "\n\n" + mergeRequestNoteMarker
+
+	gl, err := newGitlabClient(params)
+	if err != nil {
+		return err
+	}
+
+	opt := &gitlab.ListMergeRequestNotesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+	for {
+		mergeRequestNotes, resp, err := gl.Notes.ListMergeRequestNotes(params.Project, mr.IID, opt)
+		if err != nil {
+			return fmt.Errorf("failed to list notes of merge request: %w", err)
+		}
+		for _, note := range mergeRequestNotes {
+			if strings.Contains(note.Body, mergeRequestNoteMarker) {
+				if note.Body == body {
+					log.Printf("[DEBUG] Note %d is up to date", note.ID)
+					return nil
+				}
+				log.Printf("[DEBUG] Update note %d on merge request %d", note.ID, mr.IID)
+				_, _, err := gl.Notes.UpdateMergeRequestNote(params.Project, mr.IID, note.ID, &gitlab.UpdateMergeRequestNoteOptions{
+					Body: gitlab.String(body),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to update note on merge request: %w", err)
+				}
+				return nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	log.Printf("[DEBUG] Create note on merge request %d", mr.IID)
+	_, _, err = gl.Notes.CreateMergeRequestNote(params.Project, mr.IID, &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.String(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create note on merge request: %w", err)
+	}
+
+	return nil
+}
+
+// Create Gitlab release with the tag for the version. The tag name from
+// dotenv file is used as is, otherwise the tag name starts with `v`.
+// Create releases for versions from variables of the dotenv file. There is one
+// release for each component with the new version.
+func handleDotenvRelease(params handleSemverLabelsParams) error {
+	dotenvTag := func(name string) string {
+		if params.DotenvTagVar == "" {
+			return ""
+		}
+		return os.Getenv(name)
+	}
+
+	if len(params.Components) == 0 {
+		ver := os.Getenv(params.DotenvPrefix + params.DotenvVar)
+		tag := dotenvTag(params.DotenvPrefix + params.DotenvTagVar)
+		return handleRelease(ver, tag, params)
+	}
+
+	for _, component := range params.Components {
+		log.Println("[DEBUG] Component:", component.Name)
+
+		componentParams := params
+		componentParams.TagPrefix = component.TagPrefix
+
+		ver := os.Getenv(componentDotenvVar(params.DotenvPrefix+params.DotenvVar, component.Name))
+		tag := dotenvTag(componentDotenvVar(params.DotenvPrefix+params.DotenvTagVar, component.Name))
+
+		if err := handleRelease(ver, tag, componentParams); err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func handleRelease(ver string, tag string, params handleSemverLabelsParams) error {
+	if ver == "" {
+		log.Println("[WARNING] No new version. Release skipped.")
+		return nil
+	}
+
+	current, err := semver.Current(strings.TrimPrefix(ver, params.TagPrefix))
+	if err != nil {
+		return fmt.Errorf("version %s is not semver: %w", ver, err)
+	}
+
+	if params.ReleaseRef == "" {
+		return errors.New("no ref for the release")
+	}
+
+	// the tag name mirrors the style of the last tag
+	if tag == "" {
+		lastTag, err := findLastTag(params, params.TagPrefix, nil)
+		if err != nil {
+			return err
+		}
+		tag = tagName(tagNamePrefix(lastTag, params.TagPrefix), current)
+	}
+
+	vars := map[string]string{
+		"TAG":     tag,
+		"VERSION": current,
+	}
+
+	name := expandTemplate(params.ReleaseName, vars)
+	description := expandTemplate(params.ReleaseDescription, vars)
+
+	links := []*gitlab.ReleaseAssetLinkOptions{}
+	for _, link := range params.ReleaseAssetLinks {
+		linkName, linkURL, ok := strings.Cut(link, "=")
+		if !ok {
+			return fmt.Errorf("asset link %s is not in NAME=URL format", link)
+		}
+		links = append(links, &gitlab.ReleaseAssetLinkOptions{
+			Name: gitlab.String(expandTemplate(linkName, vars)),
+			URL:  gitlab.String(expandTemplate(linkURL, vars)),
+		})
+	}
+
+	gl, err := newGitlabClient(params)
+	if err != nil {
+		return err
+	}
+
+	log.Println("[DEBUG] Project:", params.Project)
+	log.Printf("[DEBUG] Create release %s with tag %s on %s", name, tag, params.ReleaseRef)
+
+	opt := &gitlab.CreateReleaseOptions{
+		Name:        gitlab.String(name),
+		TagName:     gitlab.String(tag),
+		Description: gitlab.String(description),
+		Ref:         gitlab.String(params.ReleaseRef),
+	}
+	if len(links) > 0 {
+		opt.Assets = &gitlab.ReleaseAssetsOptions{Links: links}
+	}
+
+	release, _, err := gl.Releases.CreateRelease(params.Project, opt)
+	if err != nil {
+		return fmt.Errorf("failed to create release: %w", err)
+	}
+
+	log.Println("[DEBUG] Created release:", release)
+
+	return nil
+}
+
+// Name of the dotenv variable for the component, ie. VERSION_API
+func componentDotenvVar(dotenvVar string, name string) string {
+	suffix := strings.ToUpper(regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(name, "_"))
+	return dotenvVar + "_" + suffix
+}
+
+// Read components from the config file
+func loadComponents() ([]components.Component, error) {
+	configured := map[string]components.Component{}
+	if err := viper.UnmarshalKey("components", &configured); err != nil {
+		return nil, err
+	}
+
+	result := make([]components.Component, 0, len(configured))
+	for name, component := range configured {
+		component.Name = name
+		result = append(result, component)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Merge request which triggered the bump
+type mergeRequestInfo struct {
+	IID      int
+	Labels   gitlab.Labels
+	Pipeline bool
+}
+
+func newGitlabClient(params handleSemverLabelsParams) (*gitlab.Client, error) {
+	log.Println("[DEBUG] GitLab URL:", params.GitlabUrl)
+	gl, err := gitlab.NewClient(os.Getenv(params.GitlabTokenEnv), gitlab.WithBaseURL(params.GitlabUrl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return gl, nil
+}
+
+// Fetch tags once before the last tag is found for many tag prefixes. Returns
+// params which do not fetch tags again.
+func fetchTagsOnce(params handleSemverLabelsParams) (handleSemverLabelsParams, error) {
+	if !params.FetchTags {
+		return params, nil
+	}
+
+	log.Println("[DEBUG] Fetch tags from remote:", params.RemoteName)
+
+	err := git.FetchTags(git.FetchTagsParams{
+		RepositoryPath: params.WorkTree,
+		RemoteName:     params.RemoteName,
+		GitlabToken:    os.Getenv(params.GitlabTokenEnv),
+	})
+	if err != nil {
+		return params, fmt.Errorf("cannot fetch git tags: %w", err)
+	}
+
+	params.FetchTags = false
+	return params, nil
+}
+
+func findLastTag(params handleSemverLabelsParams, tagPrefix string, onTag func(git.TagDecision)) (string, error) {
+	log.Println("[DEBUG] Find last tag for remote:", params.RemoteName)
+	if params.FetchTags {
+		log.Println("[DEBUG] Fetch tags")
+	}
+
+	tag, err := git.FindLastTag(git.FindLastTagParams{
+		RepositoryPath: params.WorkTree,
+		RemoteName:     params.RemoteName,
+		GitlabToken:    os.Getenv(params.GitlabTokenEnv),
+		FetchTags:      params.FetchTags,
+		AllTags:        params.AllTags,
+		TagOrder:       params.TagOrder,
+		TagPrefix:      tagPrefix,
+		OnTag:          onTag,
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("cannot find the last git tag: %w", err)
+	}
+
+	log.Printf("[DEBUG] Most recent tag: %v", tag)
+
+	return tag, nil
+}
+
+// Labels are checked only if the version is not bumped explicitly
+func isLabelDriven(params handleSemverLabelsParams) bool {
+	return !params.Current && !params.BumpInitial && !params.BumpPatch && !params.BumpMinor && !params.BumpMajor && !params.BumpRelease
+}
+
+func hasSource(params handleSemverLabelsParams, source string) bool {
+	for _, s := range params.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// Find the merge request either from CI variables or from the commit message.
+// Returns nil if the merge request is not found.
+func findMergeRequest(params handleSemverLabelsParams) (*mergeRequestInfo, error) {
+	mergeRequestLabels := os.Getenv("CI_MERGE_REQUEST_LABELS")
+	mergeRequestIID := os.Getenv("CI_MERGE_REQUEST_IID")
+
+	// merge request pipeline
+	if mergeRequestLabels != "" || mergeRequestIID != "" {
+		mr := &mergeRequestInfo{
+			Labels:   gitlab.Labels{},
+			Pipeline: true,
+		}
+		if mergeRequestLabels != "" {
+			mr.Labels = strings.Split(mergeRequestLabels, ",")
+		}
+		if mergeRequestIID != "" {
+			iid, err := strconv.Atoi(mergeRequestIID)
+			if err != nil {
+				return nil, fmt.Errorf("merge request number is invalid: %w", err)
+			}
+			mr.IID = iid
+		}
+		return mr, nil
+	}
+
+	commitMessage := os.Getenv("CI_COMMIT_MESSAGE")
+
+	re_mr, err := regexp.Compile(params.CommitMessageRegexp)
+	if err != nil {
+		return nil, err
+	}
+	matches := re_mr.FindStringSubmatch(commitMessage)
+
+	if len(matches) < 2 {
+		return findMergeRequestByCommit(params)
+	}
+
+	mergeRequest, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("merge request number is invalid: %w", err)
+	}
+	log.Println("[DEBUG] Merge request:", mergeRequest)
+
+	gl, err := newGitlabClient(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return getMergeRequest(gl, params, mergeRequest)
+}
+
+// Find the merged merge request associated with the commit. It is used for
+// fast-forward and squash merges without the merge commit message.
+func findMergeRequestByCommit(params handleSemverLabelsParams) (*mergeRequestInfo, error) {
+	commitSHA := os.Getenv("CI_COMMIT_SHA")
+	if commitSHA == "" {
+		log.Println("[WARNING] Merge request not found")
+		return nil, nil
+	}
+
+	// the fallback never fails, so the pipeline without the merge request is
+	// not broken by the missing token or project
+	gl, err := newGitlabClient(params)
+	if err != nil {
+		log.Println("[WARNING] Merge request not found:", err)
+		return nil, nil
+	}
+
+	mr, err := findMergedMergeRequest(gl, params, commitSHA)
+	if err != nil {
+		log.Println("[WARNING] Merge request not found:", err)
+		return nil, nil
+	}
+	if mr == nil {
+		log.Println("[WARNING] Merge request not found")
+	}
+
+	return mr, nil
+}
+
+// Find the merged merge request which contains the commit. Returns nil if the
+// merge request is not found.
+func findMergedMergeRequest(gl *gitlab.Client, params handleSemverLabelsParams, commitSHA string) (*mergeRequestInfo, error) {
+	log.Println("[DEBUG] Project:", params.Project)
+	log.Println("[DEBUG] Find merge request for commit:", commitSHA)
+	mrs, _, err := gl.Commits.ListMergeRequestsByCommit(params.Project, commitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge requests for commit: %w", err)
+	}
+
+	for _, mr := range mrs {
+		if mr.State == "merged" {
+			log.Println("[DEBUG] Found merge request:", mr)
+			return &mergeRequestInfo{
+				IID:    mr.IID,
+				Labels: mr.Labels,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func getMergeRequest(gl *gitlab.Client, params handleSemverLabelsParams, mergeRequest int) (*mergeRequestInfo, error) {
+	log.Println("[DEBUG] Project:", params.Project)
+	opt := &gitlab.GetMergeRequestsOptions{}
+	mr, _, err := gl.MergeRequests.GetMergeRequest(params.Project, mergeRequest, opt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get information about merge request: %w", err)
+	}
+
+	log.Println("[DEBUG] Found merge request:", mr)
+
+	return &mergeRequestInfo{
+		IID:    mergeRequest,
+		Labels: mr.Labels,
+	}, nil
+}
+
+// List files changed by the merge request
+func listMergeRequestFiles(params handleSemverLabelsParams, mergeRequest int) ([]string, error) {
+	gl, err := newGitlabClient(params)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+
+	opt := &gitlab.ListMergeRequestDiffsOptions{PerPage: 100}
+	for {
+		diffs, resp, err := gl.MergeRequests.ListMergeRequestDiffs(params.Project, mergeRequest, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get changes of merge request: %w", err)
+		}
+		for _, diff := range diffs {
+			files = append(files, diff.OldPath)
+			if diff.NewPath != diff.OldPath {
+				files = append(files, diff.NewPath)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	log.Println("[DEBUG] Merge request changes:", files)
+
+	return files, nil
+}
+
+func handleSemverLabels(params handleSemverLabelsParams) error {
+	if !output.IsFormat(params.OutputFormat) {
+		return fmt.Errorf("unknown output format: %s", params.OutputFormat)
+	}
+
+	if !output.IsDotenvMode(params.DotenvMode) {
+		return fmt.Errorf("unknown dotenv mode: %s", params.DotenvMode)
+	}
+
+	if len(params.Components) > 0 {
+		return handleComponents(params)
+	}
+
+	tag, err := findLastTag(params, params.TagPrefix, nil)
 	if err != nil {
 		return err
 	}
 
-	var ver string
-	prerelease := false
+	var mr *mergeRequestInfo
+
+	if isLabelDriven(params) && hasSource(params, sourceLabels) {
+		mr, err = findMergeRequest(params)
+		if err != nil {
+			return err
+		}
+		if mr == nil && len(params.Sources) == 1 {
+			return printReport(params, versionReport{PreviousTag: tag, MatchedLabels: []string{}, Reason: reasonMergeRequestNotFound})
+		}
+	}
+
+	result, err := bumpVersion(tag, mr, params)
+	if err != nil {
+		return noTagHint(params, err)
+	}
+
+	// shown by default, so the skipped release is distinguishable from the
+	// missing merge request
+	if result.Skipped {
+		log.Printf("[NOTICE] Release skipped by label %s", result.Label)
+	}
+
+	ver := result.Version
+	namePrefix := tagNamePrefix(tag, params.TagPrefix)
+
+	if params.CreateTag && ver != "" {
+		if mr != nil && mr.Pipeline {
+			log.Println("[WARNING] Merge request is not merged. Tag skipped.")
+		} else if err := createTag(params, ver, namePrefix); err != nil {
+			return err
+		}
+	}
+
+	if params.AddVersionLabel != "" && ver != "" {
+		if err := addVersionLabel(params, mr, ver, namePrefix); err != nil {
+			return err
+		}
+	}
+
+	if params.MergeRequestNote != "" && mr != nil && mr.Pipeline {
+		notes := []string{}
+		if ver != "" {
+			notes = append(notes, mergeRequestNote(params, result, namePrefix, ""))
+		}
+		if err := upsertMergeRequestNote(params, mr, notes); err != nil {
+			return err
+		}
+	}
+
+	printed, err := newPrintedVersion(params, result, tag, namePrefix, "")
+	if err != nil {
+		return err
+	}
+
+	report, err := newVersionReport(params, result, tag, namePrefix, "")
+	if err != nil {
+		return err
+	}
+
+	return printVersions(params, []printedVersion{printed}, report)
+}
+
+// Bump versions of components touched by the merge request or changed since
+// their last tags
+func handleComponents(params handleSemverLabelsParams) error {
+	var mr *mergeRequestInfo
+	var mergeRequestFiles []string
+
+	if isLabelDriven(params) && hasSource(params, sourceLabels) {
+		var err error
+		mr, err = findMergeRequest(params)
+		if err != nil {
+			return err
+		}
+		if mr == nil && len(params.Sources) == 1 {
+			return printReport(params, componentsReport{Components: []versionReport{}, Reason: reasonMergeRequestNotFound})
+		}
+
+		if mr != nil && mr.IID != 0 {
+			mergeRequestFiles, err = listMergeRequestFiles(params, mr.IID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	versions := []printedVersion{}
+	report := componentsReport{Components: []versionReport{}}
+	notes := []string{}
+
+	for _, component := range params.Components {
+		log.Println("[DEBUG] Component:", component.Name)
+
+		tag, err := findLastTag(params, component.TagPrefix, nil)
+		if err != nil {
+			return err
+		}
+
+		if !params.Current {
+			touched, err := isComponentTouched(params, component, tag, mergeRequestFiles)
+			if err != nil {
+				return err
+			}
+			if !touched {
+				log.Printf("[DEBUG] Component %s is not touched", component.Name)
+				previous, err := previousVersion(tag, component.TagPrefix)
+				if err != nil {
+					return fmt.Errorf("component %s: %w", component.Name, err)
+				}
+				report.Components = append(report.Components, versionReport{
+					Component:       component.Name,
+					PreviousTag:     tag,
+					PreviousVersion: previous,
+					MatchedLabels:   []string{},
+					Reason:          reasonNotTouched,
+				})
+				continue
+			}
+		}
+
+		componentParams := params
+		componentParams.TagPrefix = component.TagPrefix
+
+		result, err := bumpVersion(tag, mr, componentParams)
+		if errors.Is(err, errNoTag) {
+			log.Printf("[WARNING] No tag found for component %s", component.Name)
+			report.Components = append(report.Components, versionReport{
+				Component:     component.Name,
+				MatchedLabels: []string{},
+				Reason:        reasonNoTagFound,
+			})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+
+		if result.Skipped {
+			log.Printf("[NOTICE] Release of component %s skipped by label %s", component.Name, result.Label)
+		}
+
+		ver := result.Version
+		namePrefix := tagNamePrefix(tag, component.TagPrefix)
+
+		if params.CreateTag && ver != "" {
+			if mr != nil && mr.Pipeline {
+				log.Println("[WARNING] Merge request is not merged. Tag skipped.")
+			} else if err := createTag(params, ver, namePrefix); err != nil {
+				return fmt.Errorf("component %s: %w", component.Name, err)
+			}
+		}
+
+		if params.AddVersionLabel != "" && ver != "" {
+			if err := addVersionLabel(params, mr, ver, namePrefix); err != nil {
+				return fmt.Errorf("component %s: %w", component.Name, err)
+			}
+		}
+
+		componentReport, err := newVersionReport(componentParams, result, tag, namePrefix, component.Name)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+		report.Components = append(report.Components, componentReport)
+
+		if ver != "" {
+			printed, err := newPrintedVersion(componentParams, result, tag, namePrefix, component.Name)
+			if err != nil {
+				return fmt.Errorf("component %s: %w", component.Name, err)
+			}
+			versions = append(versions, printed)
+			notes = append(notes, mergeRequestNote(params, result, namePrefix, component.Name))
+		}
+	}
+
+	if params.MergeRequestNote != "" && mr != nil && mr.Pipeline {
+		if err := upsertMergeRequestNote(params, mr, notes); err != nil {
+			return err
+		}
+	}
+
+	return printVersions(params, versions, report)
+}
+
+// Next versions for each bump level
+type nextVersions struct {
+	Component  string `json:"component,omitempty" yaml:"component,omitempty"`
+	Current    string `json:"current" yaml:"current"`
+	Major      string `json:"major" yaml:"major"`
+	Minor      string `json:"minor" yaml:"minor"`
+	Patch      string `json:"patch" yaml:"patch"`
+	Prerelease string `json:"prerelease" yaml:"prerelease"`
+	// Only for the prerelease version
+	Release string `json:"release" yaml:"release"`
+	// Why there are no versions, ie. for the component without the tag
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Report about components for JSON output of the next command
+type nextComponentsReport struct {
+	Components []nextVersions `json:"components" yaml:"components"`
+}
+
+// Calculate all possible next versions from the last tag
+func findNextVersions(params handleSemverLabelsParams, tagPrefix string, component string) (nextVersions, error) {
+	tag, err := findLastTag(params, tagPrefix, nil)
+	if err != nil {
+		return nextVersions{}, err
+	}
+	if tag == "" {
+		return nextVersions{}, errNoTag
+	}
+
+	ver, err := semver.Current(strings.TrimPrefix(tag, tagPrefix))
+	if err != nil {
+		return nextVersions{}, fmt.Errorf("current tag (%s) is not semver: %w", tag, err)
+	}
+
+	prefix := versionPrefix(tag, tagPrefix)
+
+	next := nextVersions{Component: component, Current: prefix + ver}
+
+	for _, bump := range []struct {
+		target     *string
+		level      string
+		prerelease bool
+	}{
+		{&next.Major, semver.LevelMajor, false},
+		{&next.Minor, semver.LevelMinor, false},
+		{&next.Patch, semver.LevelPatch, false},
+		{&next.Prerelease, semver.LevelPatch, true},
+	} {
+		bumped, err := semver.Bump(ver, bump.level, bump.prerelease, params.PrereleaseID)
+		if err != nil {
+			return nextVersions{}, fmt.Errorf("cannot bump tag: %w", err)
+		}
+		*bump.target = prefix + bumped
+	}
+
+	parts, err := semver.Parse(ver)
+	if err != nil {
+		return nextVersions{}, err
+	}
+	if parts.Prerelease != "" {
+		released, err := semver.BumpRelease(ver)
+		if err != nil {
+			return nextVersions{}, fmt.Errorf("cannot bump tag: %w", err)
+		}
+		next.Release = prefix + released
+	}
+
+	return next, nil
+}
+
+// Print current and all possible next versions as the table or the document
+func handleNext(params handleSemverLabelsParams) error {
+	if !output.IsFormat(params.OutputFormat) {
+		return fmt.Errorf("unknown output format: %s", params.OutputFormat)
+	}
+
+	params, err := fetchTagsOnce(params)
+	if err != nil {
+		return err
+	}
+
+	nexts := []nextVersions{}
+
+	if len(params.Components) == 0 {
+		next, err := findNextVersions(params, params.TagPrefix, "")
+		if err != nil {
+			return noTagHint(params, err)
+		}
+		nexts = append(nexts, next)
+	}
+
+	for _, component := range params.Components {
+		next, err := findNextVersions(params, component.TagPrefix, component.Name)
+		if errors.Is(err, errNoTag) {
+			log.Printf("[WARNING] No tag found for component %s", component.Name)
+			next = nextVersions{Component: component.Name, Reason: reasonNoTagFound}
+		} else if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+		nexts = append(nexts, next)
+	}
+
+	if output.IsDocument(params.OutputFormat) {
+		if len(params.Components) == 0 {
+			return printReport(params, nexts[0])
+		}
+		return printReport(params, nextComponentsReport{Components: nexts})
+	}
+
+	if writer, ok := output.VarsWriters[params.OutputFormat]; ok {
+		vars := []output.Var{}
+		for _, next := range nexts {
+			varName := func(name string) string {
+				name = params.DotenvPrefix + name + "_" + params.DotenvVar
+				if next.Component != "" {
+					return componentDotenvVar(name, next.Component)
+				}
+				return name
+			}
+			vars = append(vars,
+				output.Var{Name: varName("CURRENT"), Value: next.Current},
+				output.Var{Name: varName("MAJOR"), Value: next.Major},
+				output.Var{Name: varName("MINOR"), Value: next.Minor},
+				output.Var{Name: varName("PATCH"), Value: next.Patch},
+				output.Var{Name: varName("PRERELEASE"), Value: next.Prerelease},
+				output.Var{Name: varName("RELEASE"), Value: next.Release},
+			)
+		}
+		return writer(os.Stdout, vars)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(params.Components) > 0 {
+		fmt.Fprint(w, "COMPONENT\t")
+	}
+	fmt.Fprintln(w, "CURRENT\tMAJOR\tMINOR\tPATCH\tPRERELEASE\tRELEASE")
+	for _, next := range nexts {
+		if len(params.Components) > 0 {
+			fmt.Fprintf(w, "%s\t", next.Component)
+		}
+		values := []string{next.Current, next.Major, next.Minor, next.Patch, next.Prerelease, next.Release}
+		for i, value := range values {
+			if value == "" {
+				values[i] = "-"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+// Semver tag for the history command
+type historyTag struct {
+	Component    string              `json:"component,omitempty" yaml:"component,omitempty"`
+	Tag          string              `json:"tag" yaml:"tag"`
+	Version      string              `json:"version" yaml:"version"`
+	Commit       string              `json:"commit" yaml:"commit"`
+	Date         time.Time           `json:"date" yaml:"date"`
+	Tagger       string              `json:"tagger" yaml:"tagger"`
+	Annotated    bool                `json:"annotated" yaml:"annotated"`
+	Reachable    bool                `json:"reachable" yaml:"reachable"`
+	MergeRequest *mergeRequestReport `json:"merge_request" yaml:"merge_request"`
+}
+
+// Report about tags for JSON output of the history command
+type historyReport struct {
+	Tags []historyTag `json:"tags" yaml:"tags"`
+}
+
+// List semver tags with the prefix and find merge requests for them if
+// requested
+func findHistoryTags(params handleSemverLabelsParams, gl *gitlab.Client, tagPrefix string, component string) ([]historyTag, error) {
+	log.Println("[DEBUG] List tags for remote:", params.RemoteName)
+	if params.FetchTags {
+		log.Println("[DEBUG] Fetch tags")
+	}
+
+	tags, err := git.ListTags(git.ListTagsParams{
+		RepositoryPath: params.WorkTree,
+		RemoteName:     params.RemoteName,
+		GitlabToken:    os.Getenv(params.GitlabTokenEnv),
+		FetchTags:      params.FetchTags,
+		TagPrefix:      tagPrefix,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list git tags: %w", err)
+	}
+
+	history := []historyTag{}
+
+	for _, tag := range tags {
+		ver, err := semver.Current(tag.Version)
+		if err != nil {
+			return nil, fmt.Errorf("tag (%s) is not semver: %w", tag.Name, err)
+		}
+
+		entry := historyTag{
+			Component: component,
+			Tag:       tag.Name,
+			Version:   ver,
+			Commit:    tag.Commit,
+			Date:      tag.Time,
+			Tagger:    tag.Tagger,
+			Annotated: tag.Annotated,
+			Reachable: tag.Reachable,
+		}
+
+		if gl != nil {
+			mr, err := findMergedMergeRequest(gl, params, tag.Commit)
+			if err != nil {
+				return nil, fmt.Errorf("tag %s: %w", tag.Name, err)
+			}
+			if mr != nil {
+				entry.MergeRequest = &mergeRequestReport{Project: params.Project, IID: mr.IID}
+			}
+		}
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// Print all semver tags sorted by the precedence as the table or the document
+func handleHistory(params handleSemverLabelsParams) error {
+	if params.OutputFormat != output.Text && !output.IsDocument(params.OutputFormat) {
+		return fmt.Errorf("unsupported output format for history: %s", params.OutputFormat)
+	}
+
+	params, err := fetchTagsOnce(params)
+	if err != nil {
+		return err
+	}
+
+	var gl *gitlab.Client
+	if params.MergeRequests {
+		gl, err = newGitlabClient(params)
+		if err != nil {
+			return err
+		}
+	}
+
+	history := []historyTag{}
+
+	if len(params.Components) == 0 {
+		tags, err := findHistoryTags(params, gl, params.TagPrefix, "")
+		if err != nil {
+			return err
+		}
+		history = append(history, tags...)
+	}
+
+	for _, component := range params.Components {
+		tags, err := findHistoryTags(params, gl, component.TagPrefix, component.Name)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+		history = append(history, tags...)
+	}
+
+	if output.IsDocument(params.OutputFormat) {
+		return printReport(params, historyReport{Tags: history})
+	}
+
+	yesNo := func(value bool) string {
+		if value {
+			return "yes"
+		}
+		return "no"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(params.Components) > 0 {
+		fmt.Fprint(w, "COMPONENT\t")
+	}
+	fmt.Fprint(w, "TAG\tCOMMIT\tDATE\tTAGGER\tANNOTATED\tREACHABLE")
+	if params.MergeRequests {
+		fmt.Fprint(w, "\tMERGE REQUEST")
+	}
+	fmt.Fprintln(w)
+	for _, entry := range history {
+		if len(params.Components) > 0 {
+			fmt.Fprintf(w, "%s\t", entry.Component)
+		}
+		commit := entry.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s", entry.Tag, commit, entry.Date.Format(time.RFC3339), entry.Tagger, yesNo(entry.Annotated), yesNo(entry.Reachable))
+		if params.MergeRequests {
+			mergeRequest := "-"
+			if entry.MergeRequest != nil {
+				mergeRequest = fmt.Sprintf("!%d", entry.MergeRequest.IID)
+			}
+			fmt.Fprintf(w, "\t%s", mergeRequest)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// Print the report about decisions made when the version is calculated. Only
+// reads from the repository and Gitlab are done.
+func handleExplain(params handleSemverLabelsParams) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	var mr *mergeRequestInfo
+	var mergeRequestFiles []string
 
-	for _, label := range labels {
-		if re_prerelease.MatchString(label) {
-			log.Println("[DEBUG] Bump: prerelease")
-			prerelease = true
+	if isLabelDriven(params) && hasSource(params, sourceLabels) {
+		var err error
+		mr, err = findMergeRequest(params)
+		if err != nil {
+			return err
 		}
-	}
 
-	for _, label := range labels {
-		if re_initial.MatchString(label) {
-			log.Println("[DEBUG] Bump: initial")
-			if tag != "" {
-				return errors.New("semver is already initialized")
-			}
-			if ver != "" {
-				return errors.New("more than 1 semver label")
-			}
-			ver = params.InitialVersion
-			if prerelease {
-				ver, err = semver.BumpPrerelease(ver)
-			}
+		if err := explainMergeRequest(w, params, mr); err != nil {
+			return err
+		}
+
+		if mr == nil && len(params.Sources) == 1 {
+			fmt.Fprintf(w, "No new version:\t%s\n", reasonMergeRequestNotFound)
+			return nil
+		}
+
+		if len(params.Components) > 0 && mr != nil && mr.IID != 0 {
+			mergeRequestFiles, err = listMergeRequestFiles(params, mr.IID)
 			if err != nil {
-				return fmt.Errorf("cannot bump tag: %w", err)
+				return err
 			}
 		}
-		if re_major.MatchString(label) {
-			log.Println("[DEBUG] Bump: major")
-			if tag == "" {
-				return errors.New("no tag found")
-			}
-			if ver != "" {
-				return errors.New("more than 1 semver label")
-			}
-			ver, err = semver.BumpMajor(tag, prerelease)
+	}
+
+	if len(params.Components) == 0 {
+		tag, err := explainTags(w, params)
+		if err != nil {
+			return err
+		}
+		return explainVersion(w, params, mr, tag)
+	}
+
+	for _, component := range params.Components {
+		fmt.Fprintf(w, "\nComponent:\t%s\n", component.Name)
+
+		componentParams := params
+		componentParams.TagPrefix = component.TagPrefix
+
+		tag, err := explainTags(w, componentParams)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+
+		if !params.Current {
+			touched, err := isComponentTouched(params, component, tag, mergeRequestFiles)
 			if err != nil {
-				return fmt.Errorf("cannot bump tag: %w", err)
+				return err
 			}
-		}
-		if re_minor.MatchString(label) {
-			log.Println("[DEBUG] Bump: minor")
-			if tag == "" {
-				return errors.New("no tag found")
+			if !touched {
+				fmt.Fprintf(w, "No new version:\t%s\n", reasonNotTouched)
+				continue
 			}
-			if ver != "" {
-				return errors.New("more than 1 semver label")
+		}
+
+		err = explainVersion(w, componentParams, mr, tag)
+		if errors.Is(err, errNoTag) {
+			fmt.Fprintf(w, "No new version:\t%s\n", reasonNoTagFound)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func explainMergeRequest(w io.Writer, params handleSemverLabelsParams, mr *mergeRequestInfo) error {
+	if mr == nil {
+		fmt.Fprintf(w, "Merge request:\tnot found\n")
+		return nil
+	}
+
+	if mr.IID != 0 {
+		fmt.Fprintf(w, "Merge request:\t!%d\n", mr.IID)
+	} else {
+		fmt.Fprintf(w, "Merge request:\tunknown\n")
+	}
+
+	if len(mr.Labels) == 0 {
+		fmt.Fprintf(w, "Labels:\tnone\n")
+		return nil
+	}
+
+	fmt.Fprintf(w, "Labels:\n")
+	compiledRules, err := rules.Compile(labelRules(params))
+	if err != nil {
+		return err
+	}
+	for _, label := range mr.Labels {
+		rule, ok := compiledRules.Find(label)
+		if ok {
+			fmt.Fprintf(w, "  %s\tmatched %s: %s\n", label, rule.Label, rule.Action)
+		} else {
+			fmt.Fprintf(w, "  %s\tnot matched\n", label)
+		}
+	}
+
+	return nil
+}
+
+// Print tags with decisions about them and return the last tag
+func explainTags(w io.Writer, params handleSemverLabelsParams) (string, error) {
+	fmt.Fprintf(w, "Tags:\n")
+	tag, err := findLastTag(params, params.TagPrefix, func(decision git.TagDecision) {
+		state := "rejected"
+		if decision.Accepted {
+			state = "accepted"
+		}
+		fmt.Fprintf(w, "  %s\t%s: %s\n", decision.Tag, state, decision.Reason)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if tag != "" {
+		fmt.Fprintf(w, "Last tag:\t%s\n", tag)
+	} else {
+		fmt.Fprintf(w, "Last tag:\tnone\n")
+	}
+
+	return tag, nil
+}
+
+func explainVersion(w io.Writer, params handleSemverLabelsParams, mr *mergeRequestInfo, tag string) error {
+	result, err := bumpVersion(tag, mr, params)
+	if err != nil {
+		return noTagHint(params, err)
+	}
+
+	if result.Skipped {
+		fmt.Fprintf(w, "No new version:\t%s %s\n", reasonSkipped, result.Label)
+		return nil
+	}
+
+	if result.Version == "" {
+		fmt.Fprintf(w, "No new version:\t%s\n", reasonNoLabelMatched)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Bump level:\t%s\n", result.Level)
+	if result.Source != "" {
+		fmt.Fprintf(w, "Source:\t%s\n", result.Source)
+	}
+	if result.Label != "" {
+		fmt.Fprintf(w, "Label:\t%s\n", result.Label)
+	}
+	if len(result.MatchedLabels) > 1 {
+		labels := []string{}
+		for _, match := range result.MatchedLabels {
+			labels = append(labels, match.Label)
+		}
+		fmt.Fprintf(w, "Matched labels:\t%s (%s)\n", strings.Join(labels, ", "), params.LabelConflict)
+	}
+	if result.Commit != "" {
+		fmt.Fprintf(w, "Commit:\t%s\n", result.Commit)
+	}
+	if result.Source == sourceMergeRequests && result.MergeRequest != 0 {
+		fmt.Fprintf(w, "From merge request:\t!%d\n", result.MergeRequest)
+	}
+	fmt.Fprintf(w, "Version:\t%s\n", versionPrefix(tag, params.TagPrefix)+result.Version)
+	fmt.Fprintf(w, "Tag:\t%s\n", tagName(tagNamePrefix(tag, params.TagPrefix), result.Version))
+
+	return nil
+}
+
+// Check if the component is touched by files from the merge request or by
+// files changed since the last tag of the component
+func isComponentTouched(params handleSemverLabelsParams, component components.Component, tag string, mergeRequestFiles []string) (bool, error) {
+	files := mergeRequestFiles
+	if files == nil {
+		var err error
+		files, err = git.ChangedFiles(git.ChangedFilesParams{
+			RepositoryPath: params.WorkTree,
+			Tag:            tag,
+		})
+		if err != nil {
+			return false, fmt.Errorf("cannot find changed files: %w", err)
+		}
+	}
+
+	return component.IsTouched(files)
+}
+
+// Decision made when the version is calculated
+type bumpResult struct {
+	Version       string
+	Level         string
+	Source        string
+	Label         string
+	MatchedLabels []rules.Match
+	Commit        string
+	MergeRequest  int
+	Skipped       bool
+}
+
+// Calculate the new version and append the build metadata
+func bumpVersion(tag string, mr *mergeRequestInfo, params handleSemverLabelsParams) (bumpResult, error) {
+	result, err := calculateVersion(tag, mr, params)
+	if err != nil {
+		return bumpResult{}, err
+	}
+
+	if params.BuildMetadata != "" && result.Version != "" {
+		result.Version, err = semver.SetMetadata(result.Version, expandTemplate(params.BuildMetadata, nil))
+		if err != nil {
+			return bumpResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// Calculate the new version from the tag either explicitly or based on labels
+func calculateVersion(tag string, mr *mergeRequestInfo, params handleSemverLabelsParams) (bumpResult, error) {
+	var err error
+
+	// the prerelease id implies the prerelease
+	if params.PrereleaseID != "" {
+		params.Prerelease = true
+	}
+
+	// the prefix is restored when the version is printed
+	fullTag := tag
+	tag = strings.TrimPrefix(tag, params.TagPrefix)
+
+	if params.Current {
+		if tag == "" {
+			return bumpResult{}, errNoTag
+		}
+		ver, err := semver.Current(tag)
+		if err != nil {
+			return bumpResult{}, fmt.Errorf("current tag (%s) is not semver: %w", tag, err)
+		}
+		return bumpResult{Version: ver, Level: semver.LevelCurrent}, nil
+	}
+
+	if params.BumpInitial {
+		if tag != "" {
+			return bumpResult{}, errors.New("semver is already initialized")
+		}
+
+		ver := params.InitialVersion
+
+		if params.Prerelease {
+			ver, err = semver.BumpPrerelease(ver, params.PrereleaseID)
+		}
+		if err != nil {
+			return bumpResult{}, fmt.Errorf("cannot bump tag: %w", err)
+		}
+
+		return bumpResult{Version: ver, Level: semver.LevelInitial}, nil
+	}
+
+	if params.BumpPatch || params.BumpMinor || params.BumpMajor || params.BumpRelease {
+		if tag == "" {
+			return bumpResult{}, errNoTag
+		}
+
+		result := bumpResult{Version: tag}
+
+		if params.BumpPatch {
+			result.Level = semver.LevelPatch
+			result.Version, err = semver.BumpPatch(tag, params.Prerelease, params.PrereleaseID)
+		}
+
+		if params.BumpMinor {
+			result.Level = semver.LevelMinor
+			result.Version, err = semver.BumpMinor(tag, params.Prerelease, params.PrereleaseID)
+		}
+
+		if params.BumpMajor {
+			result.Level = semver.LevelMajor
+			result.Version, err = semver.BumpMajor(tag, params.Prerelease, params.PrereleaseID)
+		}
+
+		if params.BumpRelease {
+			result.Level = semver.LevelRelease
+			result.Version, err = semver.BumpRelease(tag)
+		}
+
+		if err != nil {
+			return bumpResult{}, fmt.Errorf("cannot bump tag: %w", err)
+		}
+
+		return result, nil
+	}
+
+	// rules are compiled once for all merge requests
+	var compiledRules *rules.Compiled
+	if hasSource(params, sourceLabels) || hasSource(params, sourceMergeRequests) {
+		compiledRules, err = rules.Compile(labelRules(params))
+		if err != nil {
+			return bumpResult{}, err
+		}
+	}
+
+	var result bumpResult
+
+	for _, source := range params.Sources {
+		switch source {
+		case sourceLabels:
+			if mr != nil {
+				result, err = bumpVersionByLabels(tag, mr.Labels, compiledRules, params)
+				result.MergeRequest = mr.IID
 			}
-			ver, err = semver.BumpMinor(tag, prerelease)
+		case sourceConventionalCommits:
+			result, err = bumpVersionByCommits(tag, fullTag, params)
+		case sourceMergeRequests:
+			result, err = bumpVersionByMergeRequests(tag, fullTag, compiledRules, params)
+		default:
+			return bumpResult{}, fmt.Errorf("unknown source: %s", source)
+		}
+		if err != nil {
+			return bumpResult{}, err
+		}
+		if result.Skipped {
+			return result, nil
+		}
+		if result.Version != "" {
+			break
+		}
+	}
+
+	if params.Fail && result.Version == "" {
+		return bumpResult{}, errors.New("no label matched")
+	}
+
+	return result, nil
+}
+
+// Calculate the new version based on commits since the tag
+func bumpVersionByCommits(tag string, fullTag string, params handleSemverLabelsParams) (bumpResult, error) {
+	messages, err := git.CommitMessages(git.CommitMessagesParams{
+		RepositoryPath: params.WorkTree,
+		Tag:            fullTag,
+	})
+	if err != nil {
+		return bumpResult{}, fmt.Errorf("cannot find commits: %w", err)
+	}
+
+	result := bumpResult{Source: sourceConventionalCommits}
+
+	for _, message := range messages {
+		level := conventional.BumpLevel(message)
+		if semver.CompareLevels(level, result.Level) > 0 {
+			result.Level = level
+			result.Commit, _, _ = strings.Cut(message, "\n")
+		}
+	}
+
+	if result.Level == "" {
+		log.Println("[DEBUG] No conventional commit matched")
+		return bumpResult{}, nil
+	}
+
+	log.Printf("[DEBUG] Bump: %s from commit: %s", result.Level, result.Commit)
+
+	if tag == "" {
+		return bumpResult{}, errNoTag
+	}
+
+	result.Version, err = semver.Bump(tag, result.Level, params.Prerelease, params.PrereleaseID)
+	if err != nil {
+		return bumpResult{}, fmt.Errorf("cannot bump tag: %w", err)
+	}
+
+	return result, nil
+}
+
+// Calculate the new version based on labels of all merge requests merged
+// since the tag. The highest level wins.
+func bumpVersionByMergeRequests(tag string, fullTag string, compiledRules *rules.Compiled, params handleSemverLabelsParams) (bumpResult, error) {
+	messages, err := git.CommitMessages(git.CommitMessagesParams{
+		RepositoryPath: params.WorkTree,
+		Tag:            fullTag,
+	})
+	if err != nil {
+		return bumpResult{}, fmt.Errorf("cannot find commits: %w", err)
+	}
+
+	re_mr, err := regexp.Compile(params.CommitMessageRegexp)
+	if err != nil {
+		return bumpResult{}, err
+	}
+
+	var gl *gitlab.Client
+	seen := map[int]bool{}
+	result := bumpResult{}
+
+	for _, message := range messages {
+		matches := re_mr.FindStringSubmatch(message)
+		if len(matches) < 2 {
+			continue
+		}
+
+		mergeRequest, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return bumpResult{}, fmt.Errorf("merge request number is invalid: %w", err)
+		}
+		if seen[mergeRequest] {
+			continue
+		}
+		seen[mergeRequest] = true
+
+		log.Println("[DEBUG] Merge request:", mergeRequest)
+
+		if gl == nil {
+			gl, err = newGitlabClient(params)
 			if err != nil {
-				return fmt.Errorf("cannot bump tag: %w", err)
+				return bumpResult{}, err
 			}
 		}
-		if re_patch.MatchString(label) {
-			log.Println("[DEBUG] Bump: patch")
-			if tag == "" {
-				return errors.New("no tag found")
-			}
-			if ver != "" {
-				return errors.New("more than 1 semver label")
+
+		mr, err := getMergeRequest(gl, params, mergeRequest)
+		if err != nil {
+			return bumpResult{}, err
+		}
+
+		mrResult, err := bumpVersionByLabels(tag, mr.Labels, compiledRules, params)
+		if err != nil {
+			return bumpResult{}, fmt.Errorf("merge request %d: %w", mergeRequest, err)
+		}
+
+		if mrResult.Version != "" && (result.Version == "" || semver.CompareLevels(mrResult.Level, result.Level) > 0) {
+			result = mrResult
+			result.MergeRequest = mergeRequest
+		}
+	}
+
+	if result.Version == "" {
+		log.Println("[DEBUG] No merge request matched")
+		return bumpResult{}, nil
+	}
+
+	result.Source = sourceMergeRequests
+
+	log.Printf("[DEBUG] Bump: %s from merge request: %d", result.Level, result.MergeRequest)
+
+	return result, nil
+}
+
+// Rules for labels from the config file or from label regexps
+func labelRules(params handleSemverLabelsParams) []rules.Rule {
+	if len(params.Rules) > 0 {
+		return params.Rules
+	}
+	labelRules := []rules.Rule{}
+	// an empty regexp disables skipping as it would match any label
+	if params.SkipLabelRegexp != "" {
+		labelRules = append(labelRules, rules.Rule{Label: params.SkipLabelRegexp, Action: rules.ActionSkip})
+	}
+	if params.PrereleaseIDLabelRegexp != "" {
+		labelRules = append(labelRules, rules.Rule{Label: params.PrereleaseIDLabelRegexp, Action: rules.ActionSetPrereleaseID, PrereleaseID: "$1"})
+	}
+	return append(labelRules,
+		rules.Rule{Label: params.PrereleaseLabelRegexp, Action: rules.ActionPrerelease},
+		rules.Rule{Label: params.ReleaseLabelRegexp, Action: rules.ActionRelease},
+		rules.Rule{Label: params.InitialLabelRegexp, Action: rules.ActionInitial},
+		rules.Rule{Label: params.MajorLabelRegexp, Action: rules.ActionMajor},
+		rules.Rule{Label: params.MinorLabelRegexp, Action: rules.ActionMinor},
+		rules.Rule{Label: params.PatchLabelRegexp, Action: rules.ActionPatch},
+	)
+}
+
+// Choose the label which decides about the bump level if more than 1 label
+// matched
+func resolveLabelConflict(matches []rules.Match, labelConflict string) (rules.Match, error) {
+	decided := matches[0]
+
+	if len(matches) == 1 {
+		return decided, nil
+	}
+
+	considered := make([]string, len(matches))
+	for i, match := range matches {
+		considered[i] = fmt.Sprintf("%s (%s)", match.Label, match.Rule.Action)
+	}
+
+	switch labelConflict {
+	case labelConflictError:
+		return rules.Match{}, fmt.Errorf("more than 1 semver label: %s", strings.Join(considered, ", "))
+	case labelConflictHighest:
+		for _, match := range matches[1:] {
+			if semver.CompareLevels(match.Rule.Action, decided.Rule.Action) > 0 {
+				decided = match
 			}
-			ver, err = semver.BumpPatch(tag, prerelease)
-			if err != nil {
-				return fmt.Errorf("cannot bump tag: %w", err)
+		}
+	case labelConflictLowest:
+		for _, match := range matches[1:] {
+			if semver.CompareLevels(match.Rule.Action, decided.Rule.Action) < 0 {
+				decided = match
 			}
 		}
+	default:
+		return rules.Match{}, fmt.Errorf("unknown label conflict policy: %s", labelConflict)
+	}
+
+	log.Printf("[WARNING] More than 1 semver label: %s; %s decided", strings.Join(considered, ", "), decided.Label)
+
+	return decided, nil
+}
+
+// Calculate the new version based on labels of the merge request
+func bumpVersionByLabels(tag string, labels gitlab.Labels, compiledRules *rules.Compiled, params handleSemverLabelsParams) (bumpResult, error) {
+	var err error
+
+	log.Println("[DEBUG] Labels:", labels)
+
+	evaluated := compiledRules.Evaluate(labels)
+
+	if evaluated.SkipLabel != "" {
+		log.Println("[DEBUG] Bump: skip")
+		return bumpResult{Label: evaluated.SkipLabel, Skipped: true, Source: sourceLabels}, nil
+	}
+
+	matches := evaluated.Matches
+
+	if len(matches) == 0 {
+		return bumpResult{Source: sourceLabels}, nil
+	}
+
+	prerelease := params.Prerelease || evaluated.Prerelease
+	prereleaseID := evaluated.PrereleaseID
+	if prereleaseID == "" {
+		prereleaseID = params.PrereleaseID
+	}
+
+	decided, err := resolveLabelConflict(matches, params.LabelConflict)
+	if err != nil {
+		return bumpResult{}, err
+	}
+
+	level := decided.Rule.Action
+
+	log.Println("[DEBUG] Bump:", level)
+
+	result := bumpResult{
+		Level:         level,
+		Label:         decided.Label,
+		MatchedLabels: matches,
+		Source:        sourceLabels,
 	}
 
-	if params.Fail && ver == "" {
-		return errors.New("no label matched")
+	if level == semver.LevelInitial {
+		if tag != "" {
+			return bumpResult{}, errors.New("semver is already initialized")
+		}
+		result.Version = params.InitialVersion
+		if prerelease {
+			result.Version, err = semver.BumpPrerelease(result.Version, prereleaseID)
+		}
+	} else {
+		if tag == "" {
+			return bumpResult{}, errNoTag
+		}
+		result.Version, err = semver.Bump(tag, level, prerelease, prereleaseID)
+	}
+	if err != nil {
+		return bumpResult{}, fmt.Errorf("cannot bump tag: %w", err)
 	}
 
-	return printVersion(ver, params.DotenvFile, params.DotenvVar)
+	return result, nil
 }
@@ -0,0 +1,247 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the output
+const (
+	Dotenv       = "dotenv"
+	GithubOutput = "github-output"
+	JSON         = "json"
+	Shell        = "shell"
+	Text         = "text"
+	YAML         = "yaml"
+)
+
+// Variable with the value
+type Var struct {
+	Name  string
+	Value string
+}
+
+// Writer of variables in the format
+type VarsWriter func(w io.Writer, vars []Var) error
+
+var VarsWriters = map[string]VarsWriter{
+	Dotenv:       WriteDotenv,
+	GithubOutput: WriteGithubOutput,
+	Shell:        WriteShell,
+}
+
+func IsFormat(format string) bool {
+	switch format {
+	case Text, JSON, YAML:
+		return true
+	}
+	_, ok := VarsWriters[format]
+	return ok
+}
+
+// Formats with the single document
+func IsDocument(format string) bool {
+	return format == JSON || format == YAML
+}
+
+var safeValueRegexp = regexp.MustCompile(`^[\w.,+/:@%^=-]*$`)
+
+// Write variables in dotenv format. Values with special characters are
+// double-quoted.
+func WriteDotenv(w io.Writer, vars []Var) error {
+	for _, v := range vars {
+		value := v.Value
+		if !safeValueRegexp.MatchString(value) {
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write variables as shell assignments which can be evaluated. Values are
+// single-quoted.
+func WriteShell(w io.Writer, vars []Var) error {
+	for _, v := range vars {
+		value := "'" + strings.ReplaceAll(v.Value, "'", `'\''`) + "'"
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write variables in the format of `$GITHUB_OUTPUT` file. Multiline values use
+// the delimiter.
+func WriteGithubOutput(w io.Writer, vars []Var) error {
+	for _, v := range vars {
+		if !strings.Contains(v.Value, "\n") {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		delimiter := "EOF"
+		for strings.Contains(v.Value, delimiter) {
+			delimiter += "_"
+		}
+		if _, err := fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", v.Name, delimiter, v.Value, delimiter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the single JSON or YAML document
+func WriteDocument(w io.Writer, format string, doc any) error {
+	switch format {
+	case JSON:
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	case YAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("unknown document format: %s", format)
+}
+
+// Modes of writing the dotenv file
+const (
+	DotenvAppend    = "append"
+	DotenvMerge     = "merge"
+	DotenvOverwrite = "overwrite"
+)
+
+func IsDotenvMode(mode string) bool {
+	return mode == DotenvAppend || mode == DotenvMerge || mode == DotenvOverwrite
+}
+
+var dotenvKeyRegexp = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// Write variables to the dotenv file. The file is overwritten, the variables
+// are appended to the file, or only the lines with the same variables are
+// replaced and other lines are preserved. The file is written atomically.
+func WriteDotenvFile(path string, mode string, vars []Var) error {
+	var existing []byte
+	if mode != DotenvOverwrite {
+		var err error
+		existing, err = os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	var b bytes.Buffer
+
+	switch mode {
+	case DotenvOverwrite:
+	case DotenvAppend:
+		b.Write(existing)
+		if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+			b.WriteString("\n")
+		}
+	case DotenvMerge:
+		values := map[string]Var{}
+		for _, v := range vars {
+			values[v.Name] = v
+		}
+		written := map[string]bool{}
+		lines := strings.SplitAfter(string(existing), "\n")
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+			var v Var
+			var prefix string
+			owned := false
+			if m := dotenvKeyRegexp.FindStringSubmatch(line); m != nil {
+				prefix = m[1]
+				v, owned = values[m[2]]
+			}
+			if !owned {
+				b.WriteString(line)
+				if !strings.HasSuffix(line, "\n") {
+					b.WriteString("\n")
+				}
+				continue
+			}
+			// duplicated lines of the variable are removed and `export` is
+			// preserved
+			if !written[v.Name] {
+				b.WriteString(prefix)
+				if err := WriteDotenv(&b, []Var{v}); err != nil {
+					return err
+				}
+				written[v.Name] = true
+			}
+		}
+		remaining := []Var{}
+		for _, v := range vars {
+			if !written[v.Name] {
+				remaining = append(remaining, v)
+			}
+		}
+		vars = remaining
+	default:
+		return fmt.Errorf("unknown dotenv mode: %s", mode)
+	}
+
+	if err := WriteDotenv(&b, vars); err != nil {
+		return err
+	}
+
+	return writeFileAtomically(path, b.Bytes())
+}
+
+// Write the temporary file and rename it, so the file is never written
+// partially
+func writeFileAtomically(path string, data []byte) error {
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
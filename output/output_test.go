@@ -0,0 +1,137 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteDotenvFile(t *testing.T) {
+	vars := []Var{
+		{Name: "VERSION", Value: "1.2.3"},
+		{Name: "TAG", Value: "v1.2.3"},
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		existing *string
+		want     string
+	}{
+		{
+			name: "overwrite missing file",
+			mode: DotenvOverwrite,
+			want: "VERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "overwrite existing file",
+			mode:     DotenvOverwrite,
+			existing: ptr("OTHER=1\n"),
+			want:     "VERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+		{
+			name: "append to missing file",
+			mode: DotenvAppend,
+			want: "VERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "append to existing file",
+			mode:     DotenvAppend,
+			existing: ptr("OTHER=1\n"),
+			want:     "OTHER=1\nVERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "append without trailing newline",
+			mode:     DotenvAppend,
+			existing: ptr("OTHER=1"),
+			want:     "OTHER=1\nVERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+		{
+			name: "merge to missing file",
+			mode: DotenvMerge,
+			want: "VERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "merge replaces owned lines in place",
+			mode:     DotenvMerge,
+			existing: ptr("# comment\nVERSION=1.0.0\nOTHER=1\n"),
+			want:     "# comment\nVERSION=1.2.3\nOTHER=1\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "merge preserves export",
+			mode:     DotenvMerge,
+			existing: ptr("export VERSION=1.0.0\nOTHER=1\n"),
+			want:     "export VERSION=1.2.3\nOTHER=1\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "merge drops duplicated owned lines",
+			mode:     DotenvMerge,
+			existing: ptr("VERSION=1.0.0\nOTHER=1\nVERSION=1.1.0\n"),
+			want:     "VERSION=1.2.3\nOTHER=1\nTAG=v1.2.3\n",
+		},
+		{
+			name:     "merge without trailing newline",
+			mode:     DotenvMerge,
+			existing: ptr("OTHER=1\nTAG=v1.0.0"),
+			want:     "OTHER=1\nTAG=v1.2.3\nVERSION=1.2.3\n",
+		},
+		{
+			name:     "merge unowned last line without trailing newline",
+			mode:     DotenvMerge,
+			existing: ptr("OTHER=1"),
+			want:     "OTHER=1\nVERSION=1.2.3\nTAG=v1.2.3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "semver.env")
+		if tt.existing != nil {
+			if err := os.WriteFile(path, []byte(*tt.existing), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := WriteDotenvFile(path, tt.mode, vars); err != nil {
+			t.Errorf("%s: WriteDotenvFile() error = %v", tt.name, err)
+			continue
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: WriteDotenvFile() wrote %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteDotenvFilePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "semver.env")
+	if err := os.WriteFile(path, []byte("OTHER=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteDotenvFile(path, DotenvMerge, []Var{{Name: "VERSION", Value: "1.2.3"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("WriteDotenvFile() changed permissions to %v", info.Mode().Perm())
+	}
+}
+
+func TestWriteDotenvFileUnknownMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "semver.env")
+	if err := WriteDotenvFile(path, "replace", nil); err == nil {
+		t.Error("WriteDotenvFile() with unknown mode did not fail")
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
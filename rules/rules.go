@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+)
+
+// Actions of the rule
+const (
+	ActionInitial         = "initial"
+	ActionMajor           = "major"
+	ActionMinor           = "minor"
+	ActionPatch           = "patch"
+	ActionPrerelease      = "prerelease"
+	ActionRelease         = "release"
+	ActionSetPrereleaseID = "set-prerelease-id"
+	ActionNone            = "none"
+	ActionSkip            = "skip"
+)
+
+type Rule struct {
+	Label        string `mapstructure:"label"`
+	Action       string `mapstructure:"action"`
+	PrereleaseID string `mapstructure:"prerelease-id"`
+}
+
+// Label matched by the rule
+type Match struct {
+	Label string
+	Rule  Rule
+}
+
+type Result struct {
+	// Labels matched by rules with the bump level action
+	Matches      []Match
+	Prerelease   bool
+	PrereleaseID string
+	// Label which skips the release
+	SkipLabel string
+}
+
+// Rules with compiled regexps, so they are compiled only once
+type Compiled struct {
+	rules   []Rule
+	regexps []*regexp.Regexp
+}
+
+// Validate actions and compile regexps of rules
+func Compile(rules []Rule) (*Compiled, error) {
+	compiled := make([]*regexp.Regexp, len(rules))
+
+	for i, rule := range rules {
+		switch rule.Action {
+		case ActionInitial, ActionMajor, ActionMinor, ActionPatch, ActionPrerelease, ActionRelease, ActionNone, ActionSkip:
+		case ActionSetPrereleaseID:
+			if rule.PrereleaseID == "" {
+				return nil, fmt.Errorf("missing prerelease-id for rule %s", rule.Label)
+			}
+		default:
+			return nil, fmt.Errorf("unknown action %s for rule %s", rule.Action, rule.Label)
+		}
+
+		re, err := regexp.Compile(rule.Label)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp for rule %s: %w", rule.Label, err)
+		}
+		compiled[i] = re
+	}
+
+	return &Compiled{rules: rules, regexps: compiled}, nil
+}
+
+// Evaluate rules for labels. Rules are checked in order and the first rule
+// which matches the label is applied.
+func (c *Compiled) Evaluate(labels []string) Result {
+	result := Result{}
+
+	for _, label := range labels {
+		for i, rule := range c.rules {
+			match := c.regexps[i].FindStringSubmatchIndex(label)
+			if match == nil {
+				continue
+			}
+
+			log.Printf("[DEBUG] Label %s matched %s: %s", label, rule.Label, rule.Action)
+
+			switch rule.Action {
+			case ActionInitial, ActionMajor, ActionMinor, ActionPatch, ActionRelease:
+				result.Matches = append(result.Matches, Match{Label: label, Rule: rule})
+			case ActionPrerelease:
+				result.Prerelease = true
+			case ActionSetPrereleaseID:
+				result.Prerelease = true
+				// the prerelease id can refer to submatches of the label, ie. $1
+				result.PrereleaseID = string(c.regexps[i].ExpandString(nil, rule.PrereleaseID, label, match))
+			case ActionSkip:
+				result.SkipLabel = label
+			}
+
+			break
+		}
+	}
+
+	return result
+}
+
+// Find the first rule which matches the label
+func (c *Compiled) Find(label string) (Rule, bool) {
+	for i, rule := range c.rules {
+		if c.regexps[i].MatchString(label) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantErr bool
+	}{
+		{"valid", []Rule{{Label: "semver::major", Action: ActionMajor}}, false},
+		{"unknown action", []Rule{{Label: "semver::major", Action: "bump"}}, true},
+		{"invalid regexp", []Rule{{Label: "semver::(major", Action: ActionMajor}}, true},
+		{"missing prerelease id", []Rule{{Label: "semver::rc", Action: ActionSetPrereleaseID}}, true},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.rules)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Compile() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := []Rule{
+		{Label: `semver::none`, Action: ActionSkip},
+		{Label: `semver::(alpha|beta|rc)`, Action: ActionSetPrereleaseID, PrereleaseID: "$1"},
+		{Label: `semver::major`, Action: ActionMajor},
+		{Label: `semver::`, Action: ActionMinor},
+		{Label: `semver::major`, Action: ActionPatch},
+		{Label: `prerelease`, Action: ActionPrerelease},
+	}
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   Result
+	}{
+		{
+			name:   "no labels",
+			labels: []string{},
+			want:   Result{},
+		},
+		{
+			name:   "first matching rule in order",
+			labels: []string{"semver::major"},
+			want:   Result{Matches: []Match{{Label: "semver::major", Rule: rules[2]}}},
+		},
+		{
+			name:   "label consumed by the first rule only",
+			labels: []string{"semver::rc"},
+			want:   Result{Prerelease: true, PrereleaseID: "rc"},
+		},
+		{
+			name:   "submatch expansion",
+			labels: []string{"semver::beta", "semver::feature"},
+			want:   Result{Matches: []Match{{Label: "semver::feature", Rule: rules[3]}}, Prerelease: true, PrereleaseID: "beta"},
+		},
+		{
+			name:   "skip label",
+			labels: []string{"semver::none", "semver::major"},
+			want:   Result{Matches: []Match{{Label: "semver::major", Rule: rules[2]}}, SkipLabel: "semver::none"},
+		},
+		{
+			name:   "prerelease without id",
+			labels: []string{"prerelease", "docs"},
+			want:   Result{Prerelease: true},
+		},
+	}
+
+	compiled, err := Compile(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		got := compiled.Evaluate(tt.labels)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Evaluate(%v) = %+v, want %+v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	rules := []Rule{
+		{Label: `semver::major`, Action: ActionMajor},
+		{Label: `semver::`, Action: ActionMinor},
+	}
+
+	compiled, err := Compile(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		label  string
+		want   Rule
+		wantOk bool
+	}{
+		{"semver::major", rules[0], true},
+		{"semver::fix", rules[1], true},
+		{"docs", Rule{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := compiled.Find(tt.label)
+		if ok != tt.wantOk || got != tt.want {
+			t.Errorf("Find(%q) = %+v, %v, want %+v, %v", tt.label, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
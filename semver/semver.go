@@ -3,16 +3,84 @@ package semver
 import (
 	"fmt"
 	"log"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 )
 
+// Levels of the bump
+const (
+	LevelCurrent = "current"
+	LevelInitial = "initial"
+	LevelMajor   = "major"
+	LevelMinor   = "minor"
+	LevelPatch   = "patch"
+	LevelRelease = "release"
+)
+
+// Order of bump levels from the lowest
+var levelOrder = map[string]int{
+	LevelRelease: 1,
+	LevelPatch:   2,
+	LevelMinor:   3,
+	LevelMajor:   4,
+	LevelInitial: 5,
+}
+
+// Compare bump levels. An empty level is lower than any other.
+func CompareLevels(level1 string, level2 string) int {
+	order1, order2 := levelOrder[level1], levelOrder[level2]
+	switch {
+	case order1 < order2:
+		return -1
+	case order1 > order2:
+		return 1
+	}
+	return 0
+}
+
 func IsValid(version string) bool {
 	_, err := semver.NewVersion(version)
 	return err == nil
 }
 
+// Parts of the version
+type Parts struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Metadata   string
+}
+
+func Parse(version string) (Parts, error) {
+	ver, err := semver.NewVersion(version)
+	if err != nil {
+		return Parts{}, err
+	}
+	return Parts{
+		Major:      ver.Major(),
+		Minor:      ver.Minor(),
+		Patch:      ver.Patch(),
+		Prerelease: ver.Prerelease(),
+		Metadata:   ver.Metadata(),
+	}, nil
+}
+
+func Compare(version1 string, version2 string) (int, error) {
+	ver1, err := semver.NewVersion(version1)
+	if err != nil {
+		return 0, err
+	}
+	ver2, err := semver.NewVersion(version2)
+	if err != nil {
+		return 0, err
+	}
+	return ver1.Compare(ver2), nil
+}
+
 func incrementNumberAsString(number string) string {
 	num, err := strconv.Atoi(number)
 	if err != nil {
@@ -22,36 +90,110 @@ func incrementNumberAsString(number string) string {
 	return result
 }
 
-func BumpPrerelease(version string) (string, error) {
-	ver, err := semver.NewVersion(version)
-	if err != nil {
-		return "", err
+// Next prerelease after the current one. The last numeric identifier is
+// incremented if the prerelease id is the same as the current one, ie.
+// `rc.1` -> `rc.2`, otherwise the prerelease starts from the first number for
+// the prerelease id, ie. `beta.3` -> `rc.1`.
+func nextPrerelease(prerelease string, prereleaseID string) string {
+	if prerelease == "" {
+		return firstPrerelease(prereleaseID)
+	}
+
+	if prereleaseID == "" || prerelease == prereleaseID || strings.HasPrefix(prerelease, prereleaseID+".") {
+		identifiers := strings.Split(prerelease, ".")
+		last := identifiers[len(identifiers)-1]
+		if _, err := strconv.Atoi(last); err == nil {
+			identifiers[len(identifiers)-1] = incrementNumberAsString(last)
+			return strings.Join(identifiers, ".")
+		}
+		return prerelease + ".1"
 	}
-	log.Printf("[TRACE] BumpPrerelease(version=%v)", version)
 
-	newVer, err := ver.SetPrerelease(incrementNumberAsString(ver.Prerelease()))
+	return firstPrerelease(prereleaseID)
+}
+
+func firstPrerelease(prereleaseID string) string {
+	if prereleaseID == "" {
+		return "1"
+	}
+	return prereleaseID + ".1"
+}
+
+// Set the next prerelease for the same version. The new prerelease must have
+// higher precedence than the current prerelease, so it is not possible to
+// switch from `rc` to `beta`. The release version, ie. the initial version,
+// gets the first prerelease.
+func setNextPrerelease(ver *semver.Version, prereleaseID string) (string, error) {
+	newVer, err := ver.SetPrerelease(nextPrerelease(ver.Prerelease(), prereleaseID))
 	if err != nil {
 		return "", fmt.Errorf("cannot bump semver: %w", err)
 	}
 
+	if ver.Prerelease() != "" && !newVer.GreaterThan(ver) {
+		return "", fmt.Errorf("prerelease %s has lower precedence than %s", newVer.Prerelease(), ver.Prerelease())
+	}
+
 	return newVer.String(), nil
 }
 
-func BumpPatch(version string, prerelease bool) (string, error) {
+// Check if the prerelease version already includes the bump of the level, ie.
+// 2.0.0-rc.1 is already the major bump from 1.x.
+func isBumpedBy(ver *semver.Version, level string) bool {
+	if ver.Prerelease() == "" {
+		return false
+	}
+	switch level {
+	case LevelMajor:
+		return ver.Minor() == 0 && ver.Patch() == 0
+	case LevelMinor:
+		return ver.Patch() == 0
+	case LevelPatch:
+		return true
+	}
+	return false
+}
+
+func BumpPrerelease(version string, prereleaseID string) (string, error) {
+	ver, err := semver.NewVersion(version)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[TRACE] BumpPrerelease(version=%v, prereleaseID=%v)", version, prereleaseID)
+
+	return setNextPrerelease(ver, prereleaseID)
+}
+
+func bump(version string, level string, prerelease bool, prereleaseID string) (string, error) {
 	ver, err := semver.NewVersion(version)
 	if err != nil {
 		return "", err
 	}
-	log.Printf("[TRACE] BumpPatch(version=%v)", version)
 
-	incVer := ver.IncPatch()
+	// the next prerelease or the final release of the same version
+	if isBumpedBy(ver, level) {
+		if prerelease {
+			return setNextPrerelease(ver, prereleaseID)
+		}
+		return release(ver)
+	}
+
+	var incVer semver.Version
+	switch level {
+	case LevelMajor:
+		incVer = ver.IncMajor()
+	case LevelMinor:
+		incVer = ver.IncMinor()
+	case LevelPatch:
+		incVer = ver.IncPatch()
+	default:
+		return "", fmt.Errorf("unknown bump level: %s", level)
+	}
 
 	if !prerelease {
 		return incVer.String(), nil
 	}
 
-	newVer, err := incVer.SetPrerelease(incrementNumberAsString(ver.Prerelease()))
-
+	newVer, err := incVer.SetPrerelease(firstPrerelease(prereleaseID))
 	if err != nil {
 		return "", fmt.Errorf("cannot bump semver: %w", err)
 	}
@@ -59,45 +201,83 @@ func BumpPatch(version string, prerelease bool) (string, error) {
 	return newVer.String(), nil
 }
 
-func BumpMinor(version string, prerelease bool) (string, error) {
+func release(ver *semver.Version) (string, error) {
+	newVer, err := ver.SetPrerelease("")
+	if err != nil {
+		return "", fmt.Errorf("cannot bump semver: %w", err)
+	}
+	newVer, err = newVer.SetMetadata("")
+	if err != nil {
+		return "", fmt.Errorf("cannot bump semver: %w", err)
+	}
+	return newVer.String(), nil
+}
+
+// Finalize the prerelease version, ie. 2.0.0-rc.3 -> 2.0.0
+func BumpRelease(version string) (string, error) {
 	ver, err := semver.NewVersion(version)
 	if err != nil {
 		return "", err
 	}
-	log.Printf("[TRACE] BumpMinor(version=%v)", version)
-
-	incVer := ver.IncMinor()
+	log.Printf("[TRACE] BumpRelease(version=%v)", version)
 
-	if !prerelease {
-		return incVer.String(), nil
+	if ver.Prerelease() == "" {
+		return "", fmt.Errorf("version %s is not a prerelease", ver)
 	}
 
-	newVer, err := incVer.SetPrerelease(incrementNumberAsString(ver.Prerelease()))
+	return release(ver)
+}
 
-	if err != nil {
-		return "", fmt.Errorf("cannot bump semver: %w", err)
-	}
+func BumpPatch(version string, prerelease bool, prereleaseID string) (string, error) {
+	log.Printf("[TRACE] BumpPatch(version=%v, prerelease=%v, prereleaseID=%v)", version, prerelease, prereleaseID)
+	return bump(version, LevelPatch, prerelease, prereleaseID)
+}
 
-	return newVer.String(), nil
+func BumpMinor(version string, prerelease bool, prereleaseID string) (string, error) {
+	log.Printf("[TRACE] BumpMinor(version=%v, prerelease=%v, prereleaseID=%v)", version, prerelease, prereleaseID)
+	return bump(version, LevelMinor, prerelease, prereleaseID)
+}
+
+func BumpMajor(version string, prerelease bool, prereleaseID string) (string, error) {
+	log.Printf("[TRACE] BumpMajor(version=%v, prerelease=%v, prereleaseID=%v)", version, prerelease, prereleaseID)
+	return bump(version, LevelMajor, prerelease, prereleaseID)
 }
 
-func BumpMajor(version string, prerelease bool) (string, error) {
+// Bump major, minor or patch version
+func Bump(version string, level string, prerelease bool, prereleaseID string) (string, error) {
+	switch level {
+	case LevelMajor:
+		return BumpMajor(version, prerelease, prereleaseID)
+	case LevelMinor:
+		return BumpMinor(version, prerelease, prereleaseID)
+	case LevelPatch:
+		return BumpPatch(version, prerelease, prereleaseID)
+	case LevelRelease:
+		return BumpRelease(version)
+	}
+	return "", fmt.Errorf("unknown bump level: %s", level)
+}
+
+var metadataIdentifierRegexp = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// Set the build metadata of the version. The metadata does not affect the
+// precedence of the version.
+func SetMetadata(version string, metadata string) (string, error) {
 	ver, err := semver.NewVersion(version)
 	if err != nil {
 		return "", err
 	}
-	log.Printf("[TRACE] BumpMajor(version=%v)", version)
-
-	incVer := ver.IncMajor()
+	log.Printf("[TRACE] SetMetadata(version=%v, metadata=%v)", version, metadata)
 
-	if !prerelease {
-		return incVer.String(), nil
+	for _, identifier := range strings.Split(metadata, ".") {
+		if !metadataIdentifierRegexp.MatchString(identifier) {
+			return "", fmt.Errorf("invalid build metadata %s: identifier %q is not [0-9A-Za-z-]+", metadata, identifier)
+		}
 	}
 
-	newVer, err := incVer.SetPrerelease(incrementNumberAsString(ver.Prerelease()))
-
+	newVer, err := ver.SetMetadata(metadata)
 	if err != nil {
-		return "", fmt.Errorf("cannot bump semver: %w", err)
+		return "", fmt.Errorf("invalid build metadata %s: %w", metadata, err)
 	}
 
 	return newVer.String(), nil
@@ -0,0 +1,95 @@
+package semver
+
+import "testing"
+
+func TestBumpPrerelease(t *testing.T) {
+	tests := []struct {
+		version      string
+		prereleaseID string
+		want         string
+		wantErr      bool
+	}{
+		{"1.2.3", "", "1.2.3-1", false},
+		{"0.0.0", "rc", "0.0.0-rc.1", false},
+		{"1.2.3-1", "", "1.2.3-2", false},
+		{"1.2.3-rc.1", "rc", "1.2.3-rc.2", false},
+		{"1.2.3-rc.1", "", "1.2.3-rc.2", false},
+		{"1.2.3-beta.3", "rc", "1.2.3-rc.1", false},
+		{"1.2.3-rc.1", "beta", "", true},
+		{"1.2.3-rc", "rc", "1.2.3-rc.1", false},
+	}
+
+	for _, tt := range tests {
+		got, err := BumpPrerelease(tt.version, tt.prereleaseID)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("BumpPrerelease(%q, %q) error = %v, wantErr %v", tt.version, tt.prereleaseID, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("BumpPrerelease(%q, %q) = %q, want %q", tt.version, tt.prereleaseID, got, tt.want)
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		version      string
+		level        string
+		prerelease   bool
+		prereleaseID string
+		want         string
+		wantErr      bool
+	}{
+		{"1.2.3", LevelMajor, false, "", "2.0.0", false},
+		{"1.2.3", LevelMinor, false, "", "1.3.0", false},
+		{"1.2.3", LevelPatch, false, "", "1.2.4", false},
+		{"1.2.3", LevelMinor, true, "rc", "1.3.0-rc.1", false},
+		{"2.0.0-rc.3", LevelMajor, false, "", "2.0.0", false},
+		{"2.0.0-rc.3", LevelMinor, false, "", "2.0.0", false},
+		{"2.0.0-rc.3", LevelPatch, false, "", "2.0.0", false},
+		{"2.0.0-rc.3", LevelMajor, true, "rc", "2.0.0-rc.4", false},
+		{"2.0.0-rc.3", LevelMajor, true, "beta", "", true},
+		{"2.1.0-beta.1", LevelMajor, false, "", "3.0.0", false},
+		{"2.1.0-beta.1", LevelMajor, true, "beta", "3.0.0-beta.1", false},
+		{"2.1.0-beta.1", LevelMinor, false, "", "2.1.0", false},
+		{"2.1.0-beta.1", LevelMinor, true, "rc", "2.1.0-rc.1", false},
+		{"2.1.0-beta.1", LevelPatch, false, "", "2.1.0", false},
+		{"2.1.0-beta.1", LevelRelease, false, "", "2.1.0", false},
+		{"1.2.3", LevelRelease, false, "", "", true},
+		{"1.2.3", "unknown", false, "", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := Bump(tt.version, tt.level, tt.prerelease, tt.prereleaseID)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Bump(%q, %q, %v, %q) error = %v, wantErr %v", tt.version, tt.level, tt.prerelease, tt.prereleaseID, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Bump(%q, %q, %v, %q) = %q, want %q", tt.version, tt.level, tt.prerelease, tt.prereleaseID, got, tt.want)
+		}
+	}
+}
+
+func TestBumpRelease(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"2.0.0-rc.3", "2.0.0", false},
+		{"2.0.0-rc.3+build.5", "2.0.0", false},
+		{"1.2.3", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := BumpRelease(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("BumpRelease(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("BumpRelease(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}